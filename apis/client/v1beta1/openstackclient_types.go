@@ -18,6 +18,7 @@ package v1beta1
 
 import (
 	condition "github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -36,6 +37,38 @@ type OpenStackClientSpec struct {
 	// +kubebuilder:validation:Optional
 	// NodeSelector to target subset of worker nodes running control plane services (currently only applies to KeystoneAPI and PlacementAPI)
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// ImagePullSecrets - secrets used to pull the container images, in addition to the
+	// operator wide ones
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
+	// ImagePullPolicy - pull policy of the containers, overrides the operator wide default
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// ImagePolicy - checks the container image has to pass before it gets deployed
+	ImagePolicy ImagePolicy `json:"imagePolicy,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// InheritMetadataPrefixes - labels and annotations of this CR whose key starts with
+	// one of these prefixes are propagated to all objects created for it
+	InheritMetadataPrefixes []string `json:"inheritMetadataPrefixes,omitempty"`
+}
+
+// ImagePolicy defines checks the container images have to pass before they get deployed.
+// They apply in addition to the operator wide policy.
+type ImagePolicy struct {
+	// +kubebuilder:validation:Optional
+	// RequireDigest - image references have to be pinned by digest (image@sha256:...)
+	RequireDigest bool `json:"requireDigest,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// AllowedRegistries - if set, image references have to start with one of these
+	// registry prefixes, e.g. "quay.io" or "registry.example.com/openstack"
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
 }
 
 // OpenStackClientStatus defines the observed state of OpenStackClient
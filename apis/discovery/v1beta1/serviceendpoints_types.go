@@ -0,0 +1,96 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	condition "github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServiceEndpointsSpec defines the desired state of ServiceEndpoints
+type ServiceEndpointsSpec struct {
+	// +kubebuilder:validation:Optional
+	// Selector - if set, only infra CRs of the namespace with matching labels are aggregated
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// ServiceEndpointsStatus defines the observed state of ServiceEndpoints
+type ServiceEndpointsStatus struct {
+	// Conditions
+	Conditions condition.Conditions `json:"conditions,omitempty" optional:"true"`
+
+	// Memcached - connection information of the Memcached CRs of the namespace
+	Memcached []MemcachedEndpoint `json:"memcached,omitempty"`
+
+	// TransportURLs - connection information of the TransportURL CRs of the namespace
+	TransportURLs []TransportURLEndpoint `json:"transportURLs,omitempty"`
+}
+
+// MemcachedEndpoint - servers of a Memcached CR
+type MemcachedEndpoint struct {
+	// Name - name of the Memcached CR
+	Name string `json:"name"`
+
+	// Ready - whether the Memcached CR is ready
+	Ready bool `json:"ready"`
+
+	// ServerList - List of memcached endpoints without inet(6) prefix
+	ServerList []string `json:"serverList,omitempty"`
+
+	// ServerListWithInet - List of memcached endpoints with inet(6) prefix
+	ServerListWithInet []string `json:"serverListWithInet,omitempty"`
+}
+
+// TransportURLEndpoint - transport secret of a TransportURL CR
+type TransportURLEndpoint struct {
+	// Name - name of the TransportURL CR
+	Name string `json:"name"`
+
+	// Ready - whether the TransportURL CR is ready
+	Ready bool `json:"ready"`
+
+	// SecretName - name of the secret containing the rabbitmq transport URL
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[0].status",description="Ready"
+// +kubebuilder:printcolumn:name="Message",type="string",JSONPath=".status.conditions[0].message",description="Message"
+
+// ServiceEndpoints is the Schema for the serviceendpoints API. It aggregates the
+// connection information of the infra CRs of its namespace.
+type ServiceEndpoints struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ServiceEndpointsSpec   `json:"spec,omitempty"`
+	Status ServiceEndpointsStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ServiceEndpointsList contains a list of ServiceEndpoints
+type ServiceEndpointsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ServiceEndpoints `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ServiceEndpoints{}, &ServiceEndpointsList{})
+}
@@ -0,0 +1,127 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	condition "github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+)
+
+// Memcached Condition Types used by API objects.
+const (
+	// StandbyCondition Status=True condition which indicates that the Memcached is
+	// scaled to zero replicas with all its other resources provisioned
+	StandbyCondition condition.Type = "Standby"
+
+	// DependenciesReadyCondition Status=True condition which indicates that all CRs
+	// listed in spec.dependsOn are ready
+	DependenciesReadyCondition condition.Type = "DependenciesReady"
+
+	// ConfigTemplateLatestCondition Status=True condition which indicates that the
+	// latest config template version is in use, False if an older one is pinned
+	ConfigTemplateLatestCondition condition.Type = "ConfigTemplateLatest"
+
+	// MetricsReadyCondition Status=True condition which indicates that the exporters
+	// get scraped via a ServiceMonitor, False if the Prometheus operator is missing
+	MetricsReadyCondition condition.Type = "MetricsReady"
+
+	// DebugSidecarCondition Status=True condition which indicates that the debug
+	// sidecar requested via the debug-until annotation is added to the pods, False
+	// if its image got rejected by the image policy or its name is taken
+	DebugSidecarCondition condition.Type = "DebugSidecar"
+)
+
+// Common Messages used by API objects.
+const (
+	//
+	// Standby condition messages
+	//
+
+	// StandbyMessage
+	StandbyMessage = "Memcached is in standby, no pods are running"
+
+	//
+	// DependenciesReady condition messages
+	//
+
+	// DependenciesReadyMessage
+	DependenciesReadyMessage = "All dependencies are ready"
+
+	// DependenciesWaitingMessage
+	DependenciesWaitingMessage = "Waiting for dependencies %s"
+
+	// DependenciesReadyErrorMessage
+	DependenciesReadyErrorMessage = "Dependencies error occured %s"
+
+	//
+	// ConfigTemplateLatest condition messages
+	//
+
+	// ConfigTemplateLatestMessage
+	ConfigTemplateLatestMessage = "Latest config template version %s in use"
+
+	// ConfigTemplatePinnedMessage
+	ConfigTemplatePinnedMessage = "Config template version %s is pinned, migrate to %s by updating spec.configTemplateVersion"
+
+	//
+	// ServiceConfigReady condition messages
+	//
+
+	// AuthSecretWaitingMessage
+	AuthSecretWaitingMessage = "Waiting for the auth secret %s"
+
+	// AuthSecretMissingKeyMessage
+	AuthSecretMissingKeyMessage = "Auth secret %s is missing the %s key"
+
+	//
+	// MetricsReady condition messages
+	//
+
+	// MetricsReadyMessage
+	MetricsReadyMessage = "ServiceMonitor %s created"
+
+	// MetricsNoPrometheusOperatorMessage
+	MetricsNoPrometheusOperatorMessage = "The Prometheus operator is not installed, no ServiceMonitor created"
+
+	// MetricsReadyErrorMessage
+	MetricsReadyErrorMessage = "Metrics error occured %s"
+
+	//
+	// DebugSidecar condition messages
+	//
+
+	// DebugSidecarMessage
+	DebugSidecarMessage = "Debug sidecar added until %s"
+
+	// DebugSidecarRejectedMessage
+	DebugSidecarRejectedMessage = "Debug sidecar skipped: %s"
+
+	//
+	// DeploymentReady condition messages
+	//
+
+	// StatefulSetRecreatingMessage
+	StatefulSetRecreatingMessage = "Recreating the statefulset to change its volume claim templates"
+
+	//
+	// ExposeServiceReady condition messages
+	//
+
+	// ServiceWaitingMessage
+	ServiceWaitingMessage = "Waiting for the user provided service %s"
+
+	// ServiceIPFamilyChangingMessage
+	ServiceIPFamilyChangingMessage = "Recreating the service to change its IP family from %s to %s"
+)
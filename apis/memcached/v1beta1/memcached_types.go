@@ -18,7 +18,34 @@ package v1beta1
 
 import (
 	condition "github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// RestartedAtAnnotation - annotation on a Memcached CR, changing its value, e.g. to the
+	// current time, triggers a rolling restart of the memcached pods
+	RestartedAtAnnotation = "memcached.openstack.org/restartedAt"
+
+	// CollectDiagnosticsAnnotation - annotation on a Memcached CR, changing its value
+	// triggers collecting the stats of all pods and the rendered config into the
+	// secret named in status.diagnostics
+	CollectDiagnosticsAnnotation = "memcached.openstack.org/collect-diagnostics"
+
+	// DebugUntilAnnotation - annotation on a Memcached CR with an RFC3339 time, until
+	// then the memcached pods get a debug sidecar running the debug image configured
+	// on the operator. Adding and removing the sidecar changes the pod template, which
+	// restarts all memcached pods and so drops their caches.
+	DebugUntilAnnotation = "memcached.openstack.org/debug-until"
+
+	// AuthUsernameKey - key of the SASL username in the auth and connection secrets
+	AuthUsernameKey = "username"
+	// AuthPasswordKey - key of the SASL password in the auth and connection secrets
+	AuthPasswordKey = "password"
+	// ConnectionServersKey - key of the comma separated server list in the connection secret
+	ConnectionServersKey = "servers"
 )
 
 // MemcachedSpec defines the desired state of Memcached
@@ -30,14 +57,379 @@ type MemcachedSpec struct {
 
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:default=1
-	// Size of the memcached cluster
+	// +kubebuilder:validation:Minimum=0
+	// Size of the memcached cluster. With 0 replicas the memcached is in standby, all its
+	// resources except the pods are provisioned.
 	Replicas int32 `json:"replicas"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=1
+	// MaxUnavailable - max number or percentage of replicas a voluntary disruption, e.g. a
+	// node drain, may evict at once. No PodDisruptionBudget is created for a single replica
+	// to not block drains.
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// ServerWeights - relative weight of each replica, indexed by the replica ordinal, for
+	// consistent hashing clients when the shards differ in size. Replicas without an entry,
+	// or with an entry below 1, get the weight 1.
+	ServerWeights []int32 `json:"serverWeights,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// ScaleOutInterval - if set, replicas added on scale-out are published in the server lists
+	// one at a time, each after this interval, to avoid the cache misses of many new shards at
+	// once hitting the backends. Scale-in and the initial deployment are published immediately.
+	ScaleOutInterval *metav1.Duration `json:"scaleOutInterval,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	// ServerHostnameSuffix - domain used for the server hostnames published in the status
+	// instead of <namespace>.svc, e.g. "openstack.svc.cluster.example" for a non-default
+	// cluster domain, or a custom zone served by external DNS. The hostnames are
+	// <name>-<ordinal>.<name>.<serverHostnameSuffix>.
+	ServerHostnameSuffix string `json:"serverHostnameSuffix,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=IPv4;IPv6
+	// IPFamily - forces a single stack memcached service of the given IP family.
+	// If not set the cluster default is used, e.g. IPv6 on single stack IPv6 clusters.
+	// Changing it recreates the service, the server hostnames don't resolve meanwhile.
+	IPFamily corev1.IPFamily `json:"ipFamily,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=amd64;arm64;ppc64le;s390x
+	// Architecture - CPU architecture of the nodes the memcached pods are scheduled on, via
+	// a required node affinity. Needed on clusters mixing architectures when the image is
+	// not a multi-arch manifest list. If not set the pods can run on any node.
+	Architecture string `json:"architecture,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// RequireAntiAffinity - never schedule two replicas on the same node, replicas beyond
+	// the number of nodes stay pending. By default the replicas are only preferably spread
+	// across nodes and zones.
+	RequireAntiAffinity bool `json:"requireAntiAffinity,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// ArchitectureImages - container images per architecture, the one of the selected
+	// Architecture is used instead of containerImage. If set it must have an image for
+	// the selected Architecture.
+	ArchitectureImages map[string]string `json:"architectureImages,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Resources - compute resources of the memcached container, the memory limit should
+	// leave room above the cache size for connections and the slab overhead
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// PublishZones - publish the topology zone of the node of each server in status.servers,
+	// so zone aware clients can prefer the servers in their own zone
+	PublishZones bool `json:"publishZones,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// PriorityClassName - priority class of the memcached pods, e.g. to keep the cache
+	// from being evicted before less critical workloads under node pressure
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Tuning - hashing and slab allocator options of memcached
+	Tuning Tuning `json:"tuning,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Metrics - memcached_exporter sidecar exposing the stats of each replica to Prometheus
+	Metrics Metrics `json:"metrics,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Extstore - extends the cache of each replica to a file on disk, e.g. on NVMe, for
+	// caches larger than the memory
+	Extstore *Extstore `json:"extstore,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// DependsOn - infra CRs in the same namespace which have to be ready before the
+	// memcached pods get created
+	DependsOn []Dependency `json:"dependsOn,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// NetworkAttachments - NetworkAttachmentDefinitions in the namespace of the CR the
+	// memcached pods get attached to. memcached listens on all interfaces, the servers
+	// on each of these networks are published in status.networkServerLists.
+	NetworkAttachments []string `json:"networkAttachments,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// FailureInjection - debug options simulating failures to test the resilience of
+	// consumers. Only honored if the operator runs with MEMCACHED_FAILURE_INJECTION=true.
+	FailureInjection *FailureInjection `json:"failureInjection,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[0-9]+$`
+	// ConfigTemplateVersion - pins the version of the config templates shipped with the
+	// operator, so an operator upgrade changing the templates does not restart the pods.
+	// If not set the latest version is used.
+	ConfigTemplateVersion string `json:"configTemplateVersion,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// DefaultConfigOverwrite - replaces the default config template of the given key
+	// (config.json or memcached) for this instance. The content is rendered as a template,
+	// so operator managed parameters like {{ .memcachedPort }} still get injected and have
+	// to be kept.
+	DefaultConfigOverwrite map[string]string `json:"defaultConfigOverwrite,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// PodSecurityContext - overrides the pod level securityContext of the memcached pods
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// SecurityContext - overrides the securityContext of the memcached container.
+	// If not set the container runs as root, which requires the anyuid SCC.
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// ExtraContainers - additional containers added to the memcached pods, e.g. logging agents,
+	// debug shells or mesh proxies. Names must not clash with the containers managed by the operator.
+	ExtraContainers []corev1.Container `json:"extraContainers,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// ExtraVolumes - additional volumes added to the memcached pods, e.g. for use by ExtraContainers.
+	// Names must not clash with the volumes managed by the operator.
+	ExtraVolumes []corev1.Volume `json:"extraVolumes,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// ImagePullSecrets - secrets used to pull the container images, in addition to the
+	// operator wide ones
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
+	// ImagePullPolicy - pull policy of the containers, overrides the operator wide default
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// ImagePolicy - checks the container images have to pass before they get deployed
+	ImagePolicy ImagePolicy `json:"imagePolicy,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// AuthEnabled - require the clients to authenticate via SASL with the credentials of
+	// authSecret. SASL needs the binary protocol, which the metrics exporter doesn't support.
+	AuthEnabled bool `json:"authEnabled,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// AuthSecret - secret with the username and password keys of the SASL credentials
+	AuthSecret string `json:"authSecret,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Manage - objects of the CR the operator manages, the ones not managed have to be
+	// provided by the user and are left alone. The operator creates no RBAC objects or
+	// NetworkPolicies for the CR, so those need no toggle.
+	Manage Manage `json:"manage,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// InheritMetadataPrefixes - labels and annotations of this CR whose key starts with
+	// one of these prefixes are propagated to all objects created for it
+	InheritMetadataPrefixes []string `json:"inheritMetadataPrefixes,omitempty"`
+}
+
+// Dependency references an infra CR in the namespace of the Memcached
+type Dependency struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=Memcached;TransportURL
+	// Kind - kind of the CR
+	Kind string `json:"kind"`
+
+	// +kubebuilder:validation:Required
+	// Name - name of the CR
+	Name string `json:"name"`
+}
+
+// FailureInjection defines failures simulated for testing
+type FailureInjection struct {
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// StartDelaySeconds - delay before memcached gets started in the pods
+	StartDelaySeconds int32 `json:"startDelaySeconds,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// ReadinessFlapPeriodSeconds - if set, the pods alternate between ready and not
+	// ready with this period
+	ReadinessFlapPeriodSeconds int32 `json:"readinessFlapPeriodSeconds,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// DropServers - ordinals of replicas left out of the published server lists
+	DropServers []int32 `json:"dropServers,omitempty"`
+}
+
+// Extstore defines the disk storage of the memcached extstore
+type Extstore struct {
+	// +kubebuilder:validation:Required
+	// Size - size of the volume of each replica, the extstore file uses 90% of it, which
+	// has to be at least 64Mi. The size of the persistent volume claims can't be changed
+	// once they got created, enabling or disabling them recreates the StatefulSet.
+	Size resource.Quantity `json:"size"`
+
+	// +kubebuilder:validation:Optional
+	// StorageClass - storage class of the persistent volume claim created for each
+	// replica. Like the size it can't be changed once the claims got created.
+	StorageClass *string `json:"storageClass,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// HostPath - host directory holding the extstore files instead of persistent volume
+	// claims, each pod uses a sub directory named after it. The pods need a security
+	// context constraint allowing host path volumes.
+	HostPath string `json:"hostPath,omitempty"`
+}
+
+// Manage defines which objects of a memcached CR the operator manages. There are no
+// RBAC or NetworkPolicy toggles: the operator creates no RBAC objects for the CR, the
+// pods use a pre-existing service account, and no NetworkPolicy, so users are free to
+// provide their own.
+type Manage struct {
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=true
+	// Service - manage the headless service of the pods. If false a service with the same
+	// name has to be provided, selecting the pods and publishing their addresses before they
+	// are ready, and the operator drops its owner reference and labels from an existing one.
+	Service *bool `json:"service,omitempty"`
+}
+
+// Metrics defines the memcached_exporter sidecar of the memcached pods
+type Metrics struct {
+	// +kubebuilder:validation:Optional
+	// Enabled - deploy the exporter sidecar, and a ServiceMonitor if the Prometheus
+	// operator is installed
+	Enabled bool `json:"enabled,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="quay.io/prometheus/memcached-exporter:v0.11.1"
+	// ExporterImage - image of the memcached_exporter sidecar
+	ExporterImage string `json:"exporterImage,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="30s"
+	// +kubebuilder:validation:Pattern=`^[0-9]+(ms|s|m|h)$`
+	// Interval - interval Prometheus scrapes the exporters at
+	Interval string `json:"interval,omitempty"`
+}
+
+// Tuning defines hashing and slab allocator options of memcached, useful for workloads
+// with very small or very large objects where the defaults waste memory
+type Tuning struct {
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=murmur3;jenkins;xxh3
+	// HashAlgorithm - hash used for the item table (-o hash_algorithm)
+	HashAlgorithm string `json:"hashAlgorithm,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^(1\.0*[1-9][0-9]*|[2-9][0-9]*(\.[0-9]+)?|1[0-9]+(\.[0-9]+)?)$`
+	// SlabGrowthFactor - chunk size growth factor between slab classes (-f), e.g. "1.08"
+	// for many items of similar size. Has to be greater than 1.
+	SlabGrowthFactor string `json:"slabGrowthFactor,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// SlabReassign - allow moving memory pages between slab classes (-o slab_reassign)
+	SlabReassign bool `json:"slabReassign,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=2
+	// SlabAutomove - slab page automover mode (-o slab_automove), 0 disables it, 1 is
+	// the memcached default, 2 moves pages aggressively
+	SlabAutomove *int32 `json:"slabAutomove,omitempty"`
+}
+
+// ImagePolicy defines checks the container images have to pass before they get deployed.
+// They apply in addition to the operator wide policy.
+type ImagePolicy struct {
+	// +kubebuilder:validation:Optional
+	// RequireDigest - image references have to be pinned by digest (image@sha256:...)
+	RequireDigest bool `json:"requireDigest,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// AllowedRegistries - if set, image references have to start with one of these
+	// registry prefixes, e.g. "quay.io" or "registry.example.com/openstack"
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
 }
 
 // MemcachedStatus defines the observed state of Memcached
 type MemcachedStatus struct {
 	// Conditions
 	Conditions condition.Conditions `json:"conditions,omitempty" optional:"true"`
+
+	// Map of hashes to track e.g. the input hash of the config maps
+	Hash map[string]string `json:"hash,omitempty"`
+
+	// PublishedReplicas - number of replicas published in the server lists
+	PublishedReplicas int32 `json:"publishedReplicas,omitempty" optional:"true"`
+
+	// LastPublishTime - time the number of published replicas last changed
+	LastPublishTime *metav1.Time `json:"lastPublishTime,omitempty" optional:"true"`
+
+	// ServerList - List of memcached endpoints without inet(6) prefix
+	ServerList []string `json:"serverList,omitempty" optional:"true"`
+
+	// ServerListWithInet - List of memcached endpoints with inet(6) prefix
+	ServerListWithInet []string `json:"serverListWithInet,omitempty" optional:"true"`
+
+	// IPFamilies - IP families of the service the server lists are rendered for
+	IPFamilies []corev1.IPFamily `json:"ipFamilies,omitempty" optional:"true"`
+
+	// NetworkServerLists - List of memcached endpoints on each of the network attachments,
+	// by network attachment name
+	NetworkServerLists map[string][]string `json:"networkServerLists,omitempty" optional:"true"`
+
+	// Servers - List of memcached endpoints with the weight consistent hashing clients should use
+	Servers []MemcachedServer `json:"servers,omitempty" optional:"true"`
+
+	// ConfigTemplateVersion - version of the config templates in use
+	ConfigTemplateVersion string `json:"configTemplateVersion,omitempty" optional:"true"`
+
+	// NamePrefix - operator wide prefix the generated resources are named with
+	NamePrefix string `json:"namePrefix,omitempty" optional:"true"`
+
+	// NameSuffix - operator wide suffix the generated resources are named with
+	NameSuffix string `json:"nameSuffix,omitempty" optional:"true"`
+
+	// ConnectionSecret - secret with the servers and the SASL credentials for clients,
+	// if authentication is enabled
+	ConnectionSecret string `json:"connectionSecret,omitempty" optional:"true"`
+
+	// ClientSettings - recommended oslo.cache settings for clients of the published servers
+	ClientSettings *ClientSettings `json:"clientSettings,omitempty" optional:"true"`
+
+	// Diagnostics - last diagnostics collected on request of the collect-diagnostics annotation
+	Diagnostics *Diagnostics `json:"diagnostics,omitempty" optional:"true"`
+}
+
+// ClientSettings defines the oslo.cache memcache options recommended for the
+// clients of a memcached CR, so all consumers handle failed shards the same way
+type ClientSettings struct {
+	// DeadRetry - seconds a server marked dead is skipped, memcache_dead_retry
+	DeadRetry int32 `json:"deadRetry"`
+
+	// SocketTimeout - timeout in seconds of the server connections, memcache_socket_timeout
+	SocketTimeout string `json:"socketTimeout"`
+}
+
+// Diagnostics - a collection of diagnostics
+type Diagnostics struct {
+	// Request - value of the collect-diagnostics annotation the diagnostics were collected for
+	Request string `json:"request"`
+
+	// CollectionTime - time the diagnostics were collected
+	CollectionTime metav1.Time `json:"collectionTime"`
+
+	// SecretName - name of the secret holding the diagnostics
+	SecretName string `json:"secretName"`
+}
+
+// MemcachedServer - a memcached endpoint and its weight
+type MemcachedServer struct {
+	// Address - host:port of the memcached server
+	Address string `json:"address"`
+
+	// Weight - relative weight of the server
+	Weight int32 `json:"weight"`
+
+	// Zone - topology zone of the node the server runs on, if spec.publishZones is set
+	Zone string `json:"zone,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -67,7 +459,9 @@ func init() {
 	SchemeBuilder.Register(&Memcached{}, &MemcachedList{})
 }
 
-// IsReady - returns true if service is ready to serve requests
+// IsReady - returns true if service is ready to serve requests, or all its
+// resources are provisioned in standby
 func (instance Memcached) IsReady() bool {
-	return instance.Status.Conditions.IsTrue(condition.DeploymentReadyCondition)
+	return instance.Status.Conditions.IsTrue(condition.DeploymentReadyCondition) ||
+		instance.Status.Conditions.IsTrue(StandbyCondition)
 }
@@ -26,6 +26,42 @@ type TransportURLSpec struct {
 	// +kubebuilder:validation:Required
 	// RabbitmqClusterName the name of the Rabbitmq cluster which to configure the transport URL
 	RabbitmqClusterName string `json:"rabbitmqClusterName"`
+
+	// +kubebuilder:validation:Optional
+	// MessagingOptions - oslo.messaging rabbit driver options rendered into the
+	// messaging.conf key of the transport URL secret
+	MessagingOptions MessagingOptions `json:"messagingOptions,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// TargetNamespaces - additional namespaces the transport URL secret is replicated to,
+	// e.g. where the consuming services run. The copies are kept in sync and removed when
+	// the namespace is dropped from the list or the TransportURL is deleted. The operator
+	// only replicates into the namespaces allowed by its TRANSPORTURL_REPLICATION_NAMESPACES
+	// env var, and never overwrites secrets which are not copies of this one.
+	TargetNamespaces []string `json:"targetNamespaces,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// InheritMetadataPrefixes - labels and annotations of this CR whose key starts with
+	// one of these prefixes are propagated to all objects created for it
+	InheritMetadataPrefixes []string `json:"inheritMetadataPrefixes,omitempty"`
+}
+
+// MessagingOptions - [oslo_messaging_rabbit] options shared by all consumers of the transport URL
+type MessagingOptions struct {
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// HeartbeatTimeoutThreshold - heartbeat_timeout_threshold in seconds
+	HeartbeatTimeoutThreshold *int32 `json:"heartbeatTimeoutThreshold,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// RabbitRetryInterval - rabbit_retry_interval in seconds
+	RabbitRetryInterval *int32 `json:"rabbitRetryInterval,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// KombuReconnectDelay - kombu_reconnect_delay in seconds, e.g. "1.0"
+	KombuReconnectDelay string `json:"kombuReconnectDelay,omitempty"`
 }
 
 // TransportURLStatus defines the observed state of TransportURL
@@ -36,8 +72,37 @@ type TransportURLStatus struct {
 
 	// SecretName - name of the secret containing the rabbitmq transport URL
 	SecretName string `json:"secretName,omitempty"`
+
+	// ReplicatedNamespaces - namespaces the transport URL secret got replicated to
+	ReplicatedNamespaces []string `json:"replicatedNamespaces,omitempty"`
+
+	// SecretFormat - layout version of the keys in the secret, see TransportURLSecretFormat
+	SecretFormat string `json:"secretFormat,omitempty"`
 }
 
+// Keys of the transport URL secret. The layout is versioned by the format key,
+// consumers can check it, or status.secretFormat, before reading other keys.
+// Keys are only removed together with a format bump.
+const (
+	// TransportURLSecretFormat - current layout version of the transport URL secret
+	TransportURLSecretFormat = "1"
+
+	// TransportURLSecretFormatKey - layout version of the secret
+	TransportURLSecretFormatKey = "format"
+	// TransportURLSecretURLKey - the oslo.messaging transport URL
+	TransportURLSecretURLKey = "transport_url"
+	// TransportURLSecretUsernameKey - rabbitmq user
+	TransportURLSecretUsernameKey = "username"
+	// TransportURLSecretPasswordKey - password of the rabbitmq user
+	TransportURLSecretPasswordKey = "password"
+	// TransportURLSecretHostKey - rabbitmq host
+	TransportURLSecretHostKey = "host"
+	// TransportURLSecretPortKey - rabbitmq port
+	TransportURLSecretPortKey = "port"
+	// TransportURLSecretMessagingConfKey - optional oslo.messaging config snippet
+	TransportURLSecretMessagingConfKey = "messaging.conf"
+)
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[0].status",description="Status"
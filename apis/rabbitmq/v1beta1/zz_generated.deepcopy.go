@@ -26,12 +26,37 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MessagingOptions) DeepCopyInto(out *MessagingOptions) {
+	*out = *in
+	if in.HeartbeatTimeoutThreshold != nil {
+		in, out := &in.HeartbeatTimeoutThreshold, &out.HeartbeatTimeoutThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RabbitRetryInterval != nil {
+		in, out := &in.RabbitRetryInterval, &out.RabbitRetryInterval
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MessagingOptions.
+func (in *MessagingOptions) DeepCopy() *MessagingOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(MessagingOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TransportURL) DeepCopyInto(out *TransportURL) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -88,6 +113,17 @@ func (in *TransportURLList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TransportURLSpec) DeepCopyInto(out *TransportURLSpec) {
 	*out = *in
+	in.MessagingOptions.DeepCopyInto(&out.MessagingOptions)
+	if in.TargetNamespaces != nil {
+		in, out := &in.TargetNamespaces, &out.TargetNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InheritMetadataPrefixes != nil {
+		in, out := &in.InheritMetadataPrefixes, &out.InheritMetadataPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TransportURLSpec.
@@ -110,6 +146,11 @@ func (in *TransportURLStatus) DeepCopyInto(out *TransportURLStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ReplicatedNamespaces != nil {
+		in, out := &in.ReplicatedNamespaces, &out.ReplicatedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TransportURLStatus.
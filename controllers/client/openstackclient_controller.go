@@ -26,12 +26,18 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	clientv1beta1 "github.com/openstack-k8s-operators/infra-operator/apis/client/v1beta1"
+	"github.com/openstack-k8s-operators/infra-operator/pkg/health"
+	"github.com/openstack-k8s-operators/infra-operator/pkg/imagepolicy"
+	"github.com/openstack-k8s-operators/infra-operator/pkg/imagepull"
+	"github.com/openstack-k8s-operators/infra-operator/pkg/inherit"
+	"github.com/openstack-k8s-operators/infra-operator/pkg/inputhash"
 	"github.com/openstack-k8s-operators/infra-operator/pkg/openstackclient"
 	keystonev1 "github.com/openstack-k8s-operators/keystone-operator/api/v1beta1"
 	condition "github.com/openstack-k8s-operators/lib-common/modules/common/condition"
@@ -105,7 +111,7 @@ func (r *OpenStackClientReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 			instance.Status.Conditions.MarkTrue(condition.ReadyCondition, condition.ReadyMessage)
 		}
 
-		err := h.PatchInstance(ctx, instance)
+		err := h.PatchInstance(health.StatusContext(ctx), instance)
 		if err != nil {
 			_err = err
 			return
@@ -191,7 +197,9 @@ func (r *OpenStackClientReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	//
 	// create cm holding deployment script and render deployment script.
 	//
-	cmLabels := labels.GetLabels(instance, labels.GetGroupLabel("openstackclient"), map[string]string{})
+	inheritedLabels := inherit.Labels(instance, instance.Spec.InheritMetadataPrefixes)
+	inheritedAnnotations := inherit.Annotations(instance, instance.Spec.InheritMetadataPrefixes)
+	cmLabels := labels.GetLabels(instance, labels.GetGroupLabel("openstackclient"), inheritedLabels)
 	envVars := make(map[string]env.Setter)
 
 	cms := []util.Template{
@@ -203,6 +211,7 @@ func (r *OpenStackClientReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 			InstanceType:       instance.Kind,
 			AdditionalTemplate: map[string]string{},
 			Labels:             cmLabels,
+			Annotations:        inheritedAnnotations,
 		},
 	}
 	err = configmap.EnsureConfigMaps(ctx, h, instance, cms, &envVars)
@@ -210,13 +219,56 @@ func (r *OpenStackClientReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, err
 	}
 
-	clientLabels := map[string]string{
+	clientLabels := util.MergeStringMaps(inheritedLabels, map[string]string{
 		"app": "openstackclient",
-	}
+	})
 	pod := openstackclient.ClientPod(instance, clientLabels, configMapHash, secretHash)
+	imagepull.Apply(&pod.Spec, imagepull.OperatorDefaults(), imagepull.Settings{
+		PullSecrets: instance.Spec.ImagePullSecrets,
+		PullPolicy:  instance.Spec.ImagePullPolicy,
+	})
+
+	// Verify the provenance of the image before it gets deployed
+	err = imagepolicy.VerifyPodSpec(
+		pod.Spec,
+		imagepolicy.OperatorPolicy(),
+		imagepolicy.Policy{
+			RequireDigest:     instance.Spec.ImagePolicy.RequireDigest,
+			AllowedRegistries: instance.Spec.ImagePolicy.AllowedRegistries,
+		},
+	)
+	if err != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			clientv1beta1.OpenStackClientReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			clientv1beta1.OpenStackClientReadyErrorMessage,
+			err.Error()))
+		return ctrl.Result{}, nil
+	}
+
+	// The spec of a pod is mostly immutable, a forced reconcile recreates it
+	current := &corev1.Pod{}
+	err = r.Client.Get(ctx, types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, current)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	if err == nil && inputhash.ForceReconcileChanged(instance, current) {
+		if current.DeletionTimestamp.IsZero() {
+			util.LogForObject(h, fmt.Sprintf("Recreating pod %s on force-reconcile", current.Name), instance)
+			err = r.Client.Delete(ctx, current)
+			if err != nil && !k8s_errors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: time.Duration(5) * time.Second}, nil
+	}
 
 	op, err := controllerutil.CreateOrPatch(ctx, r.Client, pod, func() error {
 		pod.Spec.Containers[0].Image = instance.Spec.ContainerImage
+		pod.Labels = util.MergeStringMaps(pod.Labels, clientLabels)
+		pod.Annotations = util.MergeStringMaps(pod.Annotations, inheritedAnnotations)
+		inputhash.SetForceReconcile(instance, pod)
 		err := controllerutil.SetControllerReference(instance, pod, r.Scheme)
 		if err != nil {
 			return err
@@ -258,5 +310,5 @@ func (r *OpenStackClientReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&clientv1beta1.OpenStackClient{}).
 		Owns(&corev1.Pod{}).
 		Owns(&corev1.ConfigMap{}).
-		Complete(r)
+		Complete(health.Wrap("OpenStackClient", r))
 }
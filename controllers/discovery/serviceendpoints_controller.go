@@ -0,0 +1,215 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"context"
+	"sort"
+
+	"github.com/go-logr/logr"
+	condition "github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	helper "github.com/openstack-k8s-operators/lib-common/modules/common/helper"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	discoveryv1 "github.com/openstack-k8s-operators/infra-operator/apis/discovery/v1beta1"
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	rabbitmqv1beta1 "github.com/openstack-k8s-operators/infra-operator/apis/rabbitmq/v1beta1"
+	health "github.com/openstack-k8s-operators/infra-operator/pkg/health"
+)
+
+// GetClient -
+func (r *ServiceEndpointsReconciler) GetClient() client.Client {
+	return r.Client
+}
+
+// GetKClient -
+func (r *ServiceEndpointsReconciler) GetKClient() kubernetes.Interface {
+	return r.Kclient
+}
+
+// GetLogger -
+func (r *ServiceEndpointsReconciler) GetLogger() logr.Logger {
+	return r.Log
+}
+
+// GetScheme -
+func (r *ServiceEndpointsReconciler) GetScheme() *runtime.Scheme {
+	return r.Scheme
+}
+
+// ServiceEndpointsReconciler reconciles a ServiceEndpoints object
+type ServiceEndpointsReconciler struct {
+	client.Client
+	Kclient kubernetes.Interface
+	Log     logr.Logger
+	Scheme  *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=discovery.openstack.org,resources=serviceendpoints,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=discovery.openstack.org,resources=serviceendpoints/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=discovery.openstack.org,resources=serviceendpoints/finalizers,verbs=update
+//+kubebuilder:rbac:groups=memcached.openstack.org,resources=memcacheds,verbs=get;list;watch
+//+kubebuilder:rbac:groups=rabbitmq.openstack.org,resources=transporturls,verbs=get;list;watch
+
+// Reconcile - ServiceEndpoints
+func (r *ServiceEndpointsReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, _err error) {
+	_ = log.FromContext(ctx)
+
+	// Fetch the ServiceEndpoints instance
+	instance := &discoveryv1.ServiceEndpoints{}
+	err := r.Get(ctx, req.NamespacedName, instance)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Return and don't requeue
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return ctrl.Result{}, err
+	}
+
+	helper, err := helper.NewHelper(
+		instance,
+		r.Client,
+		r.Kclient,
+		r.Scheme,
+		r.Log,
+	)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Always patch the instance status when exiting this function so we can persist any changes.
+	defer func() {
+		err := helper.PatchInstance(health.StatusContext(ctx), instance)
+		if err != nil {
+			_err = err
+			return
+		}
+	}()
+
+	//
+	// initialize status
+	//
+	if instance.Status.Conditions == nil {
+		instance.Status.Conditions = condition.Conditions{}
+		cl := condition.CreateList(
+			condition.UnknownCondition(condition.InputReadyCondition, condition.InitReason, condition.InputReadyInitMessage),
+		)
+		instance.Status.Conditions.Init(&cl)
+
+		// Register overall status immediately to have an early feedback e.g. in the cli
+		return ctrl.Result{}, nil
+	}
+
+	selector := labels.Everything()
+	if instance.Spec.Selector != nil {
+		selector, err = metav1.LabelSelectorAsSelector(instance.Spec.Selector)
+		if err != nil {
+			instance.Status.Conditions.Set(condition.FalseCondition(
+				condition.InputReadyCondition,
+				condition.ErrorReason,
+				condition.SeverityWarning,
+				condition.InputReadyErrorMessage,
+				err.Error()))
+			return ctrl.Result{}, nil
+		}
+	}
+	instance.Status.Conditions.MarkTrue(condition.InputReadyCondition, condition.InputReadyMessage)
+	listOpts := []client.ListOption{
+		client.InNamespace(instance.Namespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	}
+
+	memcacheds := &memcachedv1.MemcachedList{}
+	if err := r.List(ctx, memcacheds, listOpts...); err != nil {
+		return ctrl.Result{}, err
+	}
+	instance.Status.Memcached = []discoveryv1.MemcachedEndpoint{}
+	for _, m := range memcacheds.Items {
+		instance.Status.Memcached = append(instance.Status.Memcached, discoveryv1.MemcachedEndpoint{
+			Name:               m.Name,
+			Ready:              m.IsReady(),
+			ServerList:         m.Status.ServerList,
+			ServerListWithInet: m.Status.ServerListWithInet,
+		})
+	}
+	// the list order of the cache is not stable, a changing order would patch
+	// the status, and so trigger another reconcile, every time
+	sort.Slice(instance.Status.Memcached, func(i, j int) bool {
+		return instance.Status.Memcached[i].Name < instance.Status.Memcached[j].Name
+	})
+
+	transportURLs := &rabbitmqv1beta1.TransportURLList{}
+	if err := r.List(ctx, transportURLs, listOpts...); err != nil {
+		return ctrl.Result{}, err
+	}
+	instance.Status.TransportURLs = []discoveryv1.TransportURLEndpoint{}
+	for _, t := range transportURLs.Items {
+		instance.Status.TransportURLs = append(instance.Status.TransportURLs, discoveryv1.TransportURLEndpoint{
+			Name:       t.Name,
+			Ready:      t.IsReady(),
+			SecretName: t.Status.SecretName,
+		})
+	}
+	sort.Slice(instance.Status.TransportURLs, func(i, j int) bool {
+		return instance.Status.TransportURLs[i].Name < instance.Status.TransportURLs[j].Name
+	})
+
+	instance.Status.Conditions.MarkTrue(condition.ReadyCondition, condition.ReadyMessage)
+
+	return ctrl.Result{}, nil
+}
+
+// endpointsInNamespace maps an infra CR to the ServiceEndpoints of its namespace
+func (r *ServiceEndpointsReconciler) endpointsInNamespace(obj client.Object) []reconcile.Request {
+	list := &discoveryv1.ServiceEndpointsList{}
+	if err := r.List(context.Background(), list, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Unable to list ServiceEndpoints", "namespace", obj.GetNamespace())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, e := range list.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: e.Name, Namespace: e.Namespace},
+		})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ServiceEndpointsReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&discoveryv1.ServiceEndpoints{}).
+		Watches(&source.Kind{Type: &memcachedv1.Memcached{}},
+			handler.EnqueueRequestsFromMapFunc(r.endpointsInNamespace)).
+		Watches(&source.Kind{Type: &rabbitmqv1beta1.TransportURL{}},
+			handler.EnqueueRequestsFromMapFunc(r.endpointsInNamespace)).
+		Complete(health.Wrap("ServiceEndpoints", r))
+}
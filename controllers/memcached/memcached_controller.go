@@ -19,31 +19,52 @@ package memcached
 import (
 	"time"
 
+	annotations "github.com/openstack-k8s-operators/lib-common/modules/common/annotations"
 	condition "github.com/openstack-k8s-operators/lib-common/modules/common/condition"
-	configmap "github.com/openstack-k8s-operators/lib-common/modules/common/configmap"
+	pod "github.com/openstack-k8s-operators/lib-common/modules/common/pod"
+	secret "github.com/openstack-k8s-operators/lib-common/modules/common/secret"
 	commonservice "github.com/openstack-k8s-operators/lib-common/modules/common/service"
 	commonstatefulset "github.com/openstack-k8s-operators/lib-common/modules/common/statefulset"
 
 	env "github.com/openstack-k8s-operators/lib-common/modules/common/env"
 	helper "github.com/openstack-k8s-operators/lib-common/modules/common/helper"
+	labels "github.com/openstack-k8s-operators/lib-common/modules/common/labels"
 
 	util "github.com/openstack-k8s-operators/lib-common/modules/common/util"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 
 	"context"
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	rabbitmqv1beta1 "github.com/openstack-k8s-operators/infra-operator/apis/rabbitmq/v1beta1"
+	health "github.com/openstack-k8s-operators/infra-operator/pkg/health"
+	imagepolicy "github.com/openstack-k8s-operators/infra-operator/pkg/imagepolicy"
+	imagepull "github.com/openstack-k8s-operators/infra-operator/pkg/imagepull"
+	inherit "github.com/openstack-k8s-operators/infra-operator/pkg/inherit"
+	inputhash "github.com/openstack-k8s-operators/infra-operator/pkg/inputhash"
 	memcached "github.com/openstack-k8s-operators/infra-operator/pkg/memcached"
+	naming "github.com/openstack-k8s-operators/infra-operator/pkg/naming"
+	topology "github.com/openstack-k8s-operators/infra-operator/pkg/topology"
 )
 
 // Reconciler reconciles a Memcached object
@@ -63,9 +84,18 @@ type Reconciler struct {
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;
 
+// RBAC for the CRs a memcached can depend on
+// +kubebuilder:rbac:groups=rabbitmq.openstack.org,resources=transporturls,verbs=get;list;watch
+
 // RBAC for services
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete;
 
+// RBAC for config secrets, and for removing the config maps used before
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete;
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;delete;
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
+
 // Reconcile - Memcached
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, _err error) {
 	_ = log.FromContext(ctx)
@@ -102,7 +132,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ct
 			instance.Status.Conditions.MarkTrue(condition.ReadyCondition, condition.ReadyMessage)
 		}
 
-		err := helper.PatchInstance(ctx, instance)
+		err := helper.PatchInstance(health.StatusContext(ctx), instance)
 		if err != nil {
 			_err = err
 			return
@@ -118,7 +148,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ct
 		cl := condition.CreateList(
 			// endpoint for adoption redirect
 			condition.UnknownCondition(condition.ExposeServiceReadyCondition, condition.InitReason, condition.ExposeServiceReadyInitMessage),
-			// configmap generation
+			// config secret generation
 			condition.UnknownCondition(condition.ServiceConfigReadyCondition, condition.InitReason, condition.ServiceConfigReadyInitMessage),
 			// memcache pods ready
 			condition.UnknownCondition(condition.DeploymentReadyCondition, condition.InitReason, condition.DeploymentReadyInitMessage),
@@ -134,9 +164,78 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ct
 	// Create/Update all the resources associated to this Memcached instance
 	//
 
-	// Memcached config maps
-	configMapVars := make(map[string]env.Setter)
-	err = r.generateConfigMaps(ctx, helper, instance, &configMapVars)
+	// Memcached config secrets
+	configVars := make(map[string]env.Setter)
+	err = r.generateConfigs(ctx, helper, instance, &configVars)
+	if err != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			condition.ServiceConfigReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			condition.ServiceConfigReadyErrorMessage,
+			err.Error()))
+		return ctrl.Result{}, fmt.Errorf("error calculating config secret hash: %v", err)
+	}
+
+	// SASL credentials, the pods get rolled to pick up new ones
+	var authSecret *corev1.Secret
+	if instance.Spec.AuthEnabled {
+		var authHash string
+		authSecret, authHash, err = secret.GetSecret(ctx, helper, instance.Spec.AuthSecret, instance.Namespace)
+		if k8s_errors.IsNotFound(err) {
+			instance.Status.Conditions.Set(condition.FalseCondition(
+				condition.ServiceConfigReadyCondition,
+				condition.RequestedReason,
+				condition.SeverityInfo,
+				memcachedv1.AuthSecretWaitingMessage,
+				instance.Spec.AuthSecret))
+			return ctrl.Result{RequeueAfter: time.Duration(10) * time.Second}, nil
+		}
+		if err != nil {
+			instance.Status.Conditions.Set(condition.FalseCondition(
+				condition.ServiceConfigReadyCondition,
+				condition.ErrorReason,
+				condition.SeverityWarning,
+				condition.ServiceConfigReadyErrorMessage,
+				err.Error()))
+			return ctrl.Result{}, err
+		}
+		for _, key := range []string{memcachedv1.AuthUsernameKey, memcachedv1.AuthPasswordKey} {
+			if len(authSecret.Data[key]) == 0 {
+				instance.Status.Conditions.Set(condition.FalseCondition(
+					condition.ServiceConfigReadyCondition,
+					condition.ErrorReason,
+					condition.SeverityWarning,
+					memcachedv1.AuthSecretMissingKeyMessage,
+					instance.Spec.AuthSecret,
+					key))
+				return ctrl.Result{}, nil
+			}
+		}
+		configVars[instance.Spec.AuthSecret] = env.SetValue(authHash)
+	}
+
+	// Combined hash of all inputs, a change of any of them rolls the pods
+	inputhash.AddForceReconcile(instance, configVars)
+	if restartedAt, ok := instance.Annotations[memcachedv1.RestartedAtAnnotation]; ok {
+		configVars[memcachedv1.RestartedAtAnnotation] = env.SetValue(restartedAt)
+	}
+	inputHash, hashMap, changed, err := inputhash.Create(instance.Status.Hash, configVars)
+	if err != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			condition.ServiceConfigReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			condition.ServiceConfigReadyErrorMessage,
+			err.Error()))
+		return ctrl.Result{}, err
+	}
+	if changed {
+		instance.Status.Hash = hashMap
+		r.Log.Info(fmt.Sprintf("Input maps hash %s - %s", inputhash.HashName, inputHash))
+	}
+
+	nadAnnotations, err := annotations.GetNADAnnotation(instance.Namespace, instance.Spec.NetworkAttachments)
 	if err != nil {
 		instance.Status.Conditions.Set(condition.FalseCondition(
 			condition.ServiceConfigReadyCondition,
@@ -144,80 +243,868 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ct
 			condition.SeverityWarning,
 			condition.ServiceConfigReadyErrorMessage,
 			err.Error()))
-		return ctrl.Result{}, fmt.Errorf("error calculating configmap hash: %v", err)
+		return ctrl.Result{}, err
+	}
+	if len(instance.Spec.NetworkAttachments) == 0 {
+		nadAnnotations = nil
+	}
+
+	sfs := memcached.StatefulSet(instance, inputHash, nadAnnotations)
+	debug, debugLeft, err := memcached.DebugContainer(instance, time.Now())
+	if err != nil {
+		// a bad debug request must not block the deployment
+		r.Log.Error(err, "Ignoring debug request", "memcached", instance.Name)
+	}
+	imagePolicies := []imagepolicy.Policy{
+		imagepolicy.OperatorPolicy(),
+		{
+			RequireDigest:     instance.Spec.ImagePolicy.RequireDigest,
+			AllowedRegistries: instance.Spec.ImagePolicy.AllowedRegistries,
+		},
+	}
+	if debug != nil {
+		// a rejected debug image must not block the deployment either
+		err = imagepolicy.VerifyPodSpec(corev1.PodSpec{Containers: []corev1.Container{*debug}}, imagePolicies...)
+		for _, c := range instance.Spec.ExtraContainers {
+			if c.Name == debug.Name {
+				err = fmt.Errorf("extra container %s clashes with the debug sidecar", c.Name)
+			}
+		}
+		if err != nil {
+			instance.Status.Conditions.Set(condition.FalseCondition(
+				memcachedv1.DebugSidecarCondition,
+				condition.ErrorReason,
+				condition.SeverityWarning,
+				memcachedv1.DebugSidecarRejectedMessage,
+				err.Error()))
+		} else {
+			sfs.Spec.Template.Spec.Containers = append(sfs.Spec.Template.Spec.Containers, *debug)
+			instance.Status.Conditions.MarkTrue(memcachedv1.DebugSidecarCondition, memcachedv1.DebugSidecarMessage,
+				instance.Annotations[memcachedv1.DebugUntilAnnotation])
+		}
+	} else {
+		instance.Status.Conditions.Remove(memcachedv1.DebugSidecarCondition)
+	}
+	imagepull.Apply(&sfs.Spec.Template.Spec, imagepull.OperatorDefaults(), imagepull.Settings{
+		PullSecrets: instance.Spec.ImagePullSecrets,
+		PullPolicy:  instance.Spec.ImagePullPolicy,
+	})
+
+	// Verify the provenance of the images before they get rendered into the pod template
+	err = imagepolicy.VerifyPodSpec(sfs.Spec.Template.Spec, imagePolicies...)
+	if err != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			condition.ServiceConfigReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			condition.ServiceConfigReadyErrorMessage,
+			err.Error()))
+		return ctrl.Result{}, nil
+	}
+	// The generated names must be valid and not clash with resources of other CRs
+	err = memcached.ValidateNames(instance)
+	if err == nil {
+		err = naming.VerifyOwner(ctx, r.Client, &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: sfs.Name, Namespace: sfs.Namespace}}, instance)
+	}
+	if err == nil && memcached.ManagesService(instance) {
+		err = naming.VerifyOwner(ctx, r.Client, &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: sfs.Spec.ServiceName, Namespace: sfs.Namespace}}, instance)
+	}
+	if err != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			condition.ServiceConfigReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			condition.ServiceConfigReadyErrorMessage,
+			err.Error()))
+		return ctrl.Result{}, nil
 	}
 	instance.Status.Conditions.MarkTrue(condition.ServiceConfigReadyCondition, condition.ServiceConfigReadyMessage)
 
-	// Service to expose Memcached pods
-	commonsvc := commonservice.NewService(memcached.HeadlessService(instance), map[string]string{}, time.Duration(5)*time.Second)
-	sres, serr := commonsvc.CreateOrPatch(ctx, helper)
-	if serr != nil {
+	// Resources named before the operator prefix or suffix changed are not
+	// adopted under the new names, remove them
+	err = r.deleteRenamedResources(ctx, instance)
+	if err != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			condition.DeploymentReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			condition.DeploymentReadyErrorMessage,
+			err.Error()))
+		return ctrl.Result{}, err
+	}
+
+	// The primary IP family of a service is immutable, recreate the service when it changes
+	changing, err := r.recreateServiceForIPFamily(ctx, helper, instance)
+	if err != nil {
 		instance.Status.Conditions.Set(condition.FalseCondition(
 			condition.ExposeServiceReadyCondition,
 			condition.ErrorReason,
 			condition.SeverityWarning,
 			condition.ExposeServiceReadyErrorMessage,
 			err.Error()))
+		return ctrl.Result{}, err
+	}
+	if changing {
+		return ctrl.Result{RequeueAfter: time.Duration(5) * time.Second}, nil
+	}
+
+	// Service to expose Memcached pods, unless the user provides it
+	var sres ctrl.Result
+	var serr error
+	if memcached.ManagesService(instance) {
+		commonsvc := commonservice.NewService(memcached.HeadlessService(instance), map[string]string{}, time.Duration(5)*time.Second)
+		sres, serr = commonsvc.CreateOrPatch(ctx, helper)
+	} else {
+		serr = r.releaseService(ctx, instance)
+	}
+	if serr != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			condition.ExposeServiceReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			condition.ExposeServiceReadyErrorMessage,
+			serr.Error()))
 		return sres, serr
 	}
+
+	// Publish the servers with the inet(6) prefix matching the family the service got
+	svc, err := commonservice.GetServiceWithName(ctx, helper, memcached.ResourceName(instance), instance.Namespace)
+	if k8s_errors.IsNotFound(err) {
+		if !memcached.ManagesService(instance) {
+			instance.Status.Conditions.Set(condition.FalseCondition(
+				condition.ExposeServiceReadyCondition,
+				condition.RequestedReason,
+				condition.SeverityInfo,
+				memcachedv1.ServiceWaitingMessage,
+				memcached.ResourceName(instance)))
+		}
+		// not yet in the cache after creation
+		return ctrl.Result{RequeueAfter: time.Duration(5) * time.Second}, nil
+	}
+	if err != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			condition.ExposeServiceReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			condition.ExposeServiceReadyErrorMessage,
+			err.Error()))
+		return ctrl.Result{}, err
+	}
+	ipFamily := corev1.IPv4Protocol
+	if len(svc.Spec.IPFamilies) > 0 {
+		ipFamily = svc.Spec.IPFamilies[0]
+	}
+	if len(instance.Status.IPFamilies) > 0 && !reflect.DeepEqual(instance.Status.IPFamilies, svc.Spec.IPFamilies) {
+		r.Log.Info("Service IP families changed, re-rendering the server lists",
+			"memcached", instance.Name, "from", instance.Status.IPFamilies, "to", svc.Spec.IPFamilies)
+	}
+	instance.Status.IPFamilies = svc.Spec.IPFamilies
+	// Stagger the publishing of new shards if requested
+	now := time.Now()
+	published, nextPublish := memcached.PublishedReplicas(instance, now)
+	if published != instance.Status.PublishedReplicas {
+		instance.Status.PublishedReplicas = published
+		instance.Status.LastPublishTime = &metav1.Time{Time: now}
+	}
+	instance.Status.ServerList, instance.Status.ServerListWithInet = memcached.GetServerLists(instance, published, ipFamily)
+	instance.Status.Servers = memcached.GetServers(instance, instance.Status.ServerList)
+	instance.Status.ClientSettings = memcached.GetClientSettings(instance, published)
+	memcached.InjectServerFailures(instance)
 	instance.Status.Conditions.MarkTrue(condition.ExposeServiceReadyCondition, condition.ExposeServiceReadyMessage)
 
+	// Connection secret with the servers and credentials for the clients
+	err = r.reconcileConnectionSecret(ctx, instance, authSecret)
+	if err != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			condition.ExposeServiceReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			condition.ExposeServiceReadyErrorMessage,
+			err.Error()))
+		return ctrl.Result{}, err
+	}
+
+	// ServiceMonitor for the exporter sidecars
+	err = r.reconcileServiceMonitor(ctx, instance, svc.Labels)
+	if err != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			memcachedv1.MetricsReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			memcachedv1.MetricsReadyErrorMessage,
+			err.Error()))
+		return ctrl.Result{}, err
+	}
+
+	// Reject user provided sidecars and volumes that would replace managed ones
+	err = memcached.ValidateExtras(instance)
+	if err != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			condition.DeploymentReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			condition.DeploymentReadyErrorMessage,
+			err.Error()))
+		return ctrl.Result{}, nil
+	}
+
+	// Hold off the pods until the CRs this one depends on are ready
+	waiting, err := r.waitingDependencies(ctx, instance)
+	if err != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			memcachedv1.DependenciesReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			memcachedv1.DependenciesReadyErrorMessage,
+			err.Error()))
+		return ctrl.Result{}, err
+	}
+	if len(waiting) > 0 {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			memcachedv1.DependenciesReadyCondition,
+			condition.RequestedReason,
+			condition.SeverityInfo,
+			memcachedv1.DependenciesWaitingMessage,
+			strings.Join(waiting, ", ")))
+		return ctrl.Result{RequeueAfter: time.Duration(10) * time.Second}, nil
+	}
+	if len(instance.Spec.DependsOn) > 0 {
+		instance.Status.Conditions.MarkTrue(memcachedv1.DependenciesReadyCondition, memcachedv1.DependenciesReadyMessage)
+	} else {
+		instance.Status.Conditions.Remove(memcachedv1.DependenciesReadyCondition)
+	}
+
+	// Standby replaces DeploymentReady while no pods are requested
+	if instance.Spec.Replicas == 0 {
+		instance.Status.Conditions.Remove(condition.DeploymentReadyCondition)
+	} else {
+		instance.Status.Conditions.Remove(memcachedv1.StandbyCondition)
+		if !instance.Status.Conditions.Has(condition.DeploymentReadyCondition) {
+			instance.Status.Conditions.Set(condition.UnknownCondition(
+				condition.DeploymentReadyCondition, condition.InitReason, condition.DeploymentReadyInitMessage))
+			instance.Status.Conditions.MarkUnknown(condition.ReadyCondition, condition.InitReason, condition.ReadyInitMessage)
+		}
+	}
+
+	// Volume claim templates can't be patched, the statefulset gets recreated
+	// keeping its pods when claims are added or removed
+	current := &appsv1.StatefulSet{}
+	err = r.Get(ctx, types.NamespacedName{Name: sfs.Name, Namespace: sfs.Namespace}, current)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	if err == nil {
+		if current.DeletionTimestamp != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5) * time.Second}, nil
+		}
+		recreate, err := memcached.VolumeClaimTemplatesChanged(current, sfs)
+		if err != nil {
+			instance.Status.Conditions.Set(condition.FalseCondition(
+				condition.DeploymentReadyCondition,
+				condition.ErrorReason,
+				condition.SeverityWarning,
+				condition.DeploymentReadyErrorMessage,
+				err.Error()))
+			return ctrl.Result{}, nil
+		}
+		if recreate {
+			r.Log.Info("Recreating statefulset to change its volume claim templates", "memcached", instance.Name)
+			instance.Status.Conditions.Set(condition.FalseCondition(
+				condition.DeploymentReadyCondition,
+				condition.RequestedReason,
+				condition.SeverityInfo,
+				memcachedv1.StatefulSetRecreatingMessage))
+			err = r.Delete(ctx, current, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+			if err != nil && !k8s_errors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: time.Duration(5) * time.Second}, nil
+		}
+	}
+
 	// Statefulset for stable names
-	commonstatefulset := commonstatefulset.NewStatefulSet(memcached.StatefulSet(instance), time.Duration(5)*time.Second)
+	commonstatefulset := commonstatefulset.NewStatefulSet(sfs, time.Duration(5)*time.Second)
 	sfres, sferr := commonstatefulset.CreateOrPatch(ctx, helper)
 	if sferr != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			condition.DeploymentReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			condition.DeploymentReadyErrorMessage,
+			sferr.Error()))
 		return sfres, sferr
 	}
 	statefulset := commonstatefulset.GetStatefulSet()
 
+	// Keep node drains from evicting all replicas at once
+	err = r.reconcilePodDisruptionBudget(ctx, instance, sfs.Spec.Selector.MatchLabels)
+	if err != nil {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			condition.DeploymentReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			condition.DeploymentReadyErrorMessage,
+			err.Error()))
+		return ctrl.Result{}, err
+	}
+
 	//
 	// Reconstruct the state of the galera resource based on the replicaset and its pods
 	//
 
-	if statefulset.Status.ReadyReplicas > 0 {
+	// Publish the servers on each additional network once the pods got addresses
+	// there, and the zones the servers run in
+	if len(instance.Spec.NetworkAttachments) > 0 || instance.Spec.PublishZones {
+		pods, err := pod.GetPodListWithLabel(ctx, helper, instance.Namespace, sfs.Spec.Selector.MatchLabels)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		instance.Status.NetworkServerLists, err = memcached.GetNetworkServerLists(instance, pods.Items)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if instance.Spec.PublishZones {
+			zones, err := topology.PodZones(ctx, r.Client, pods.Items)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			memcached.SetServerZones(instance, zones)
+		}
+	} else {
+		instance.Status.NetworkServerLists = nil
+	}
+
+	// Collect diagnostics on request
+	if request, ok := instance.Annotations[memcachedv1.CollectDiagnosticsAnnotation]; ok &&
+		(instance.Status.Diagnostics == nil || instance.Status.Diagnostics.Request != request) {
+		err = r.collectDiagnostics(ctx, helper, instance, sfs.Spec.Selector.MatchLabels)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		instance.Status.Diagnostics = &memcachedv1.Diagnostics{
+			Request:        request,
+			CollectionTime: metav1.Now(),
+			SecretName:     memcached.DiagnosticsSecretName(instance),
+		}
+	}
+
+	if instance.Spec.Replicas == 0 {
+		instance.Status.Conditions.MarkTrue(memcachedv1.StandbyCondition, memcachedv1.StandbyMessage)
+	} else if statefulset.Status.ReadyReplicas > 0 {
 		instance.Status.Conditions.MarkTrue(condition.DeploymentReadyCondition, condition.DeploymentReadyMessage)
 	}
 
-	return ctrl.Result{}, nil
+	// come back when the next shard is due or the debug sidecar expires
+	requeueAfter := nextPublish
+	if debugLeft > 0 && (requeueAfter == 0 || debugLeft < requeueAfter) {
+		requeueAfter = debugLeft
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
-// generateConfigMaps returns the config map resource for a galera instance
-func (r *Reconciler) generateConfigMaps(
+// reconcileConnectionSecret publishes the servers and the SASL credentials of
+// the auth secret in the connection secret of the instance, or deletes it if
+// authentication is disabled
+func (r *Reconciler) reconcileConnectionSecret(
+	ctx context.Context,
+	instance *memcachedv1.Memcached,
+	authSecret *corev1.Secret,
+) error {
+	if authSecret == nil {
+		instance.Status.ConnectionSecret = ""
+		conn := &corev1.Secret{}
+		err := r.Client.Get(ctx, types.NamespacedName{Name: memcached.ConnectionSecretName(instance), Namespace: instance.Namespace}, conn)
+		if k8s_errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = r.Client.Delete(ctx, conn)
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return fmt.Errorf("error deleting connection secret %s: %w", conn.Name, err)
+		}
+		return nil
+	}
+
+	conn := memcached.ConnectionSecret(instance, instance.Status.ServerList, authSecret)
+	data := conn.Data
+	connLabels := conn.Labels
+	connAnnotations := conn.Annotations
+	_, err := controllerutil.CreateOrPatch(ctx, r.Client, conn, func() error {
+		conn.Labels = util.MergeStringMaps(conn.Labels, connLabels)
+		conn.Annotations = util.MergeStringMaps(conn.Annotations, connAnnotations)
+		conn.Data = data
+		return controllerutil.SetControllerReference(instance, conn, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("error creating connection secret %s: %w", conn.Name, err)
+	}
+	instance.Status.ConnectionSecret = conn.Name
+
+	return nil
+}
+
+// reconcilePodDisruptionBudget creates the PodDisruptionBudget of the instance,
+// or deletes it if there is at most one replica as it would block node drains
+func (r *Reconciler) reconcilePodDisruptionBudget(
+	ctx context.Context,
+	instance *memcachedv1.Memcached,
+	podLabels map[string]string,
+) error {
+	pdb := memcached.PodDisruptionBudget(instance, podLabels)
+	if instance.Spec.Replicas <= 1 {
+		err := r.Client.Get(ctx, types.NamespacedName{Name: pdb.Name, Namespace: pdb.Namespace}, pdb)
+		if k8s_errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = r.Client.Delete(ctx, pdb)
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return fmt.Errorf("error deleting PodDisruptionBudget %s: %w", pdb.Name, err)
+		}
+		return nil
+	}
+
+	spec := pdb.Spec
+	pdbLabels := pdb.Labels
+	pdbAnnotations := pdb.Annotations
+	_, err := controllerutil.CreateOrPatch(ctx, r.Client, pdb, func() error {
+		pdb.Labels = util.MergeStringMaps(pdb.Labels, pdbLabels)
+		pdb.Annotations = util.MergeStringMaps(pdb.Annotations, pdbAnnotations)
+		pdb.Spec.MaxUnavailable = spec.MaxUnavailable
+		pdb.Spec.Selector = spec.Selector
+		return controllerutil.SetControllerReference(instance, pdb, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("error creating PodDisruptionBudget %s: %w", pdb.Name, err)
+	}
+
+	return nil
+}
+
+// reconcileServiceMonitor creates the ServiceMonitor of the instance if metrics
+// are enabled and the Prometheus operator is installed, and deletes it otherwise
+func (r *Reconciler) reconcileServiceMonitor(
+	ctx context.Context,
+	instance *memcachedv1.Memcached,
+	serviceLabels map[string]string,
+) error {
+	_, err := r.Client.RESTMapper().RESTMapping(memcached.ServiceMonitorGVK.GroupKind(), memcached.ServiceMonitorGVK.Version)
+	if meta.IsNoMatchError(err) {
+		if instance.Spec.Metrics.Enabled {
+			instance.Status.Conditions.Set(condition.FalseCondition(
+				memcachedv1.MetricsReadyCondition,
+				condition.RequestedReason,
+				condition.SeverityInfo,
+				memcachedv1.MetricsNoPrometheusOperatorMessage))
+		} else {
+			instance.Status.Conditions.Remove(memcachedv1.MetricsReadyCondition)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	sm := memcached.ServiceMonitor(instance, serviceLabels)
+	if !instance.Spec.Metrics.Enabled {
+		instance.Status.Conditions.Remove(memcachedv1.MetricsReadyCondition)
+		err = r.Client.Get(ctx, types.NamespacedName{Name: sm.GetName(), Namespace: sm.GetNamespace()}, sm)
+		if k8s_errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !metav1.IsControlledBy(sm, instance) {
+			// not ours, leave it alone
+			return nil
+		}
+		err = r.Client.Delete(ctx, sm)
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return fmt.Errorf("error deleting ServiceMonitor %s: %w", sm.GetName(), err)
+		}
+		return nil
+	}
+
+	spec := sm.Object["spec"]
+	_, err = controllerutil.CreateOrPatch(ctx, r.Client, sm, func() error {
+		sm.SetLabels(util.MergeStringMaps(sm.GetLabels(), serviceLabels))
+		sm.Object["spec"] = spec
+		return controllerutil.SetControllerReference(instance, sm, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("error creating ServiceMonitor %s: %w", sm.GetName(), err)
+	}
+	instance.Status.Conditions.MarkTrue(memcachedv1.MetricsReadyCondition, memcachedv1.MetricsReadyMessage, sm.GetName())
+
+	return nil
+}
+
+// releaseService drops the owner reference of the instance from its service
+// once the user took over managing it, so it is kept when the CR is deleted.
+// The owner labels get dropped as well, otherwise the orphan auditor would
+// adopt the service again.
+func (r *Reconciler) releaseService(ctx context.Context, instance *memcachedv1.Memcached) error {
+	svc := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Name: memcached.ResourceName(instance), Namespace: instance.Namespace}, svc)
+	if k8s_errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	refs := []metav1.OwnerReference{}
+	for _, ref := range svc.OwnerReferences {
+		if ref.UID != instance.UID {
+			refs = append(refs, ref)
+		}
+	}
+	ownerLabels := []string{
+		labels.GetOwnerUIDLabelSelector("memcached"),
+		labels.GetOwnerNameSpaceLabelSelector("memcached"),
+		labels.GetOwnerNameLabelSelector("memcached"),
+	}
+	labeled := false
+	for _, l := range ownerLabels {
+		if _, ok := svc.Labels[l]; ok {
+			labeled = true
+		}
+	}
+	if len(refs) == len(svc.OwnerReferences) && !labeled {
+		return nil
+	}
+	patch := client.MergeFrom(svc.DeepCopy())
+	svc.OwnerReferences = refs
+	for _, l := range ownerLabels {
+		delete(svc.Labels, l)
+	}
+	err = r.Patch(ctx, svc, patch)
+	if err != nil {
+		return fmt.Errorf("error releasing service %s: %w", svc.Name, err)
+	}
+	r.Log.Info("Released service to the user", "memcached", instance.Name, "service", svc.Name)
+
+	return nil
+}
+
+// recreateServiceForIPFamily deletes the service of the instance if its primary
+// IP family differs from the requested one, which can't be patched. Returns
+// true while the service gets recreated.
+func (r *Reconciler) recreateServiceForIPFamily(
+	ctx context.Context,
+	h *helper.Helper,
+	instance *memcachedv1.Memcached,
+) (bool, error) {
+	if instance.Spec.IPFamily == "" || !memcached.ManagesService(instance) {
+		return false, nil
+	}
+	svc, err := commonservice.GetServiceWithName(ctx, h, memcached.ResourceName(instance), instance.Namespace)
+	if k8s_errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if len(svc.Spec.IPFamilies) == 0 || svc.Spec.IPFamilies[0] == instance.Spec.IPFamily {
+		return false, nil
+	}
+
+	r.Log.Info("Recreating service to change its IP family", "memcached", instance.Name,
+		"from", svc.Spec.IPFamilies[0], "to", instance.Spec.IPFamily)
+	instance.Status.Conditions.Set(condition.FalseCondition(
+		condition.ExposeServiceReadyCondition,
+		condition.RequestedReason,
+		condition.SeverityInfo,
+		memcachedv1.ServiceIPFamilyChangingMessage,
+		svc.Spec.IPFamilies[0], instance.Spec.IPFamily))
+	err = h.GetClient().Delete(ctx, svc)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return false, fmt.Errorf("error deleting service %s: %w", svc.Name, err)
+	}
+
+	return true, nil
+}
+
+// deleteRenamedResources deletes the resources of the instance named with the
+// affixes recorded in its status, if the operator affixes changed since
+func (r *Reconciler) deleteRenamedResources(
+	ctx context.Context,
+	instance *memcachedv1.Memcached,
+) error {
+	old := naming.Affixes{Prefix: instance.Status.NamePrefix, Suffix: instance.Status.NameSuffix}
+	current := naming.Current()
+	if old == current {
+		return nil
+	}
+
+	renamed := memcached.NamedObjects(instance, current)
+	for i, obj := range memcached.NamedObjects(instance, old) {
+		if obj.GetName() == renamed[i].GetName() {
+			continue
+		}
+		err := r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj)
+		if k8s_errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !metav1.IsControlledBy(obj, instance) {
+			// released or not ours, leave it alone
+			continue
+		}
+		r.Log.Info("Deleting renamed resource", "memcached", instance.Name,
+			"kind", fmt.Sprintf("%T", obj), "name", obj.GetName())
+		err = r.Delete(ctx, obj)
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return fmt.Errorf("error deleting %s: %w", obj.GetName(), err)
+		}
+	}
+
+	instance.Status.NamePrefix = current.Prefix
+	instance.Status.NameSuffix = current.Suffix
+	return nil
+}
+
+// collectDiagnostics stores the stats of all memcached pods and the rendered
+// config of the instance in its diagnostics secret
+func (r *Reconciler) collectDiagnostics(
+	ctx context.Context,
+	h *helper.Helper,
+	instance *memcachedv1.Memcached,
+	podLabels map[string]string,
+) error {
+	data := map[string][]byte{}
+
+	pods, err := pod.GetPodListWithLabel(ctx, h, instance.Namespace, podLabels)
+	if err != nil {
+		return err
+	}
+	for _, p := range pods.Items {
+		key := fmt.Sprintf("stats-%s", p.Name)
+		if p.Status.PodIP == "" {
+			data[key] = []byte("pod has no IP\n")
+			continue
+		}
+		stats, err := memcached.Stats(ctx, p.Status.PodIP)
+		if err != nil {
+			// an unreachable pod is a diagnostic as well
+			stats = fmt.Sprintf("error collecting stats: %s\n", err)
+		}
+		data[key] = []byte(stats)
+	}
+
+	config := &corev1.Secret{}
+	err = r.Get(ctx, types.NamespacedName{Name: memcached.ConfigSecretName(instance), Namespace: instance.Namespace}, config)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return err
+	}
+	for k, v := range config.Data {
+		data["config-"+k] = v
+	}
+
+	diag := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      memcached.DiagnosticsSecretName(instance),
+			Namespace: instance.Namespace,
+		},
+	}
+	_, err = controllerutil.CreateOrPatch(ctx, r.Client, diag, func() error {
+		diag.Labels = util.MergeStringMaps(diag.Labels, inherit.Labels(instance, instance.Spec.InheritMetadataPrefixes))
+		diag.Annotations = util.MergeStringMaps(diag.Annotations, inherit.Annotations(instance, instance.Spec.InheritMetadataPrefixes))
+		diag.Data = data
+		return controllerutil.SetControllerReference(instance, diag, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("error storing diagnostics: %w", err)
+	}
+
+	return nil
+}
+
+// waitingDependencies returns the Kind/Name of the dependencies of the
+// instance which are missing or not ready
+func (r *Reconciler) waitingDependencies(ctx context.Context, instance *memcachedv1.Memcached) ([]string, error) {
+	waiting := []string{}
+	for _, dep := range instance.Spec.DependsOn {
+		key := types.NamespacedName{Name: dep.Name, Namespace: instance.Namespace}
+		ready := false
+		var err error
+		switch dep.Kind {
+		case "Memcached":
+			obj := &memcachedv1.Memcached{}
+			if err = r.Get(ctx, key, obj); err == nil {
+				ready = obj.IsReady()
+			}
+		case "TransportURL":
+			obj := &rabbitmqv1beta1.TransportURL{}
+			if err = r.Get(ctx, key, obj); err == nil {
+				ready = obj.IsReady()
+			}
+		default:
+			return nil, fmt.Errorf("unsupported dependency kind %s", dep.Kind)
+		}
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return nil, err
+		}
+		if !ready {
+			waiting = append(waiting, dep.Kind+"/"+dep.Name)
+		}
+	}
+
+	return waiting, nil
+}
+
+// generateConfigs renders the config secret for a memcached instance
+func (r *Reconciler) generateConfigs(
 	ctx context.Context,
 	h *helper.Helper,
 	instance *memcachedv1.Memcached,
 	envVars *map[string]env.Setter,
 ) error {
-	templateParameters := make(map[string]interface{})
+	templateParameters := map[string]interface{}{
+		"memcachedPort":    memcached.MemcachedPort,
+		"memcachedOptions": memcached.Options(instance),
+	}
 	customData := make(map[string]string)
 
-	cms := []util.Template{
-		// ConfigMap
+	version, err := memcached.GetConfigTemplateVersion(instance)
+	if err != nil {
+		return err
+	}
+
+	err = memcached.ValidateDefaultConfigOverwrite(instance)
+	if err != nil {
+		return err
+	}
+	err = memcached.ValidateAuth(instance)
+	if err != nil {
+		return err
+	}
+	err = memcached.ValidateExtstore(instance)
+	if err != nil {
+		return err
+	}
+	err = memcached.ValidateArchitecture(instance)
+	if err != nil {
+		return err
+	}
+	if instance.Spec.AuthEnabled {
+		customData[memcached.SASLConfigKey] = memcached.SASLConfig()
+	}
+	for key, data := range instance.Spec.DefaultConfigOverwrite {
+		rendered, err := util.ExecuteTemplateData(data, templateParameters)
+		if err != nil {
+			return fmt.Errorf("error rendering defaultConfigOverwrite %s: %w", key, err)
+		}
+		customData[key] = rendered
+	}
+
+	sts := []util.Template{
+		// Secret, the config may contain credentials and TLS key paths
 		{
-			Name:          fmt.Sprintf("%s-memcached-config-data", instance.Name),
+			Name:          memcached.ConfigSecretName(instance),
 			Namespace:     instance.Namespace,
 			Type:          util.TemplateTypeConfig,
 			InstanceType:  instance.Kind,
+			Version:       version,
 			CustomData:    customData,
 			ConfigOptions: templateParameters,
-			Labels:        map[string]string{},
+			Labels:        inherit.Labels(instance, instance.Spec.InheritMetadataPrefixes),
+			Annotations:   inherit.Annotations(instance, instance.Spec.InheritMetadataPrefixes),
 		},
 	}
 
-	err := configmap.EnsureConfigMaps(ctx, h, instance, cms, envVars)
+	err = secret.EnsureSecrets(ctx, h, instance, sts, envVars)
 	if err != nil {
-		util.LogErrorForObject(h, err, "Unable to retrieve or create config maps", instance)
+		util.LogErrorForObject(h, err, "Unable to retrieve or create config secrets", instance)
 		return err
 	}
+	// EnsureSecrets sets the annotations only on create, keep the inherited ones current
+	config := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      memcached.ConfigSecretName(instance),
+			Namespace: instance.Namespace,
+		},
+	}
+	_, err = controllerutil.CreateOrPatch(ctx, h.GetClient(), config, func() error {
+		config.Annotations = util.MergeStringMaps(config.Annotations, inherit.Annotations(instance, instance.Spec.InheritMetadataPrefixes))
+		return controllerutil.SetControllerReference(instance, config, h.GetScheme())
+	})
+	if err != nil {
+		return fmt.Errorf("error updating config secret %s: %w", config.Name, err)
+	}
+
+	instance.Status.ConfigTemplateVersion = version
+	if version == memcached.ConfigTemplateVersion {
+		instance.Status.Conditions.MarkTrue(memcachedv1.ConfigTemplateLatestCondition,
+			memcachedv1.ConfigTemplateLatestMessage, version)
+	} else {
+		instance.Status.Conditions.Set(condition.FalseCondition(
+			memcachedv1.ConfigTemplateLatestCondition,
+			condition.RequestedReason,
+			condition.SeverityInfo,
+			memcachedv1.ConfigTemplatePinnedMessage,
+			version, memcached.ConfigTemplateVersion))
+	}
+
+	// Remove the config map the config was rendered to before it moved to a secret,
+	// looked up via the cache to not send a delete on every reconcile
+	legacy := &corev1.ConfigMap{}
+	err = h.GetClient().Get(ctx, types.NamespacedName{
+		Name:      fmt.Sprintf("%s-memcached-config-data", instance.Name),
+		Namespace: instance.Namespace,
+	}, legacy)
+	if k8s_errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = h.GetClient().Delete(ctx, legacy)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return fmt.Errorf("error deleting legacy config map %s: %w", legacy.Name, err)
+	}
 
 	return nil
 }
 
+// memcachedsForAuthSecret maps an auth secret to the Memcached instances
+// referencing it, the secret is not owned so a change would go unnoticed.
+func (r *Reconciler) memcachedsForAuthSecret(obj client.Object) []reconcile.Request {
+	list := &memcachedv1.MemcachedList{}
+	if err := r.List(context.Background(), list, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Unable to list Memcacheds", "namespace", obj.GetNamespace())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, m := range list.Items {
+		if !m.Spec.AuthEnabled || m.Spec.AuthSecret != obj.GetName() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: m.Name, Namespace: m.Namespace},
+		})
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&memcachedv1.Memcached{}).
 		Owns(&appsv1.StatefulSet{}).
 		Owns(&corev1.Service{}).
-		Complete(r)
+		Owns(&corev1.Secret{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(r.memcachedsForAuthSecret)).
+		Complete(health.Wrap("Memcached", r))
 }
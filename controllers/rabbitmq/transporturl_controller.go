@@ -19,6 +19,8 @@ package rabbitmq
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -30,8 +32,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	rabbitmqv1beta1 "github.com/openstack-k8s-operators/infra-operator/apis/rabbitmq/v1beta1"
+	health "github.com/openstack-k8s-operators/infra-operator/pkg/health"
+	inherit "github.com/openstack-k8s-operators/infra-operator/pkg/inherit"
+	inputhash "github.com/openstack-k8s-operators/infra-operator/pkg/inputhash"
+	naming "github.com/openstack-k8s-operators/infra-operator/pkg/naming"
 	condition "github.com/openstack-k8s-operators/lib-common/modules/common/condition"
 	helper "github.com/openstack-k8s-operators/lib-common/modules/common/helper"
+	labels "github.com/openstack-k8s-operators/lib-common/modules/common/labels"
 	oko_secret "github.com/openstack-k8s-operators/lib-common/modules/common/secret"
 	"github.com/openstack-k8s-operators/lib-common/modules/common/util"
 	rabbitmqv1 "github.com/rabbitmq/cluster-operator/api/v1beta1"
@@ -41,6 +48,25 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+// rabbitmqPort - AMQP port of the rabbitmq cluster
+const rabbitmqPort = 5672
+
+// ReplicationNamespacesEnv - operator env var, comma separated list of the
+// namespaces transport URL secrets may be replicated to, "*" allows all of them.
+// If not set no secrets get replicated.
+const ReplicationNamespacesEnv = "TRANSPORTURL_REPLICATION_NAMESPACES"
+
+// replicationAllowed returns true if the operator allows replicating transport
+// URL secrets into namespace
+func replicationAllowed(namespace string) bool {
+	for _, ns := range strings.Split(os.Getenv(ReplicationNamespacesEnv), ",") {
+		if ns = strings.TrimSpace(ns); ns == "*" || ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
 // GetClient -
 func (r *TransportURLReconciler) GetClient() client.Client {
 	return r.Client
@@ -134,16 +160,43 @@ func (r *TransportURLReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		if changed := helper.GetChanges()["status"]; changed {
 			patch := client.MergeFrom(helper.GetBeforeObject())
 
-			if err := r.Status().Patch(ctx, instance, patch); err != nil && !k8s_errors.IsNotFound(err) {
+			if err := r.Status().Patch(health.StatusContext(ctx), instance, patch); err != nil && !k8s_errors.IsNotFound(err) {
 				util.LogErrorForObject(helper, err, "Update status", instance)
 			}
 		}
 	}()
 
+	// Handle the cleanup of the replicated secrets on delete
+	if !instance.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, instance, helper)
+	}
+
 	return r.reconcileNormal(ctx, instance, helper)
 
 }
 
+func (r *TransportURLReconciler) reconcileDelete(ctx context.Context, instance *rabbitmqv1beta1.TransportURL, helper *helper.Helper) (ctrl.Result, error) {
+	// the secret in the namespace of the CR is garbage collected via the owner reference
+	name := instance.Status.SecretName
+	if name == "" {
+		name = transportURLSecretName(instance)
+	}
+	for _, ns := range instance.Status.ReplicatedNamespaces {
+		if err := r.deleteReplicatedSecret(ctx, instance, name, ns); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	instance.Status.ReplicatedNamespaces = nil
+
+	if controllerutil.RemoveFinalizer(instance, helper.GetFinalizer()) {
+		if err := r.Update(ctx, instance); err != nil && !k8s_errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
 func (r *TransportURLReconciler) reconcileNormal(ctx context.Context, instance *rabbitmqv1beta1.TransportURL, helper *helper.Helper) (ctrl.Result, error) {
 
 	//TODO (implement a watch on the rabbitmq cluster resources to update things if there are changes)
@@ -212,7 +265,18 @@ func (r *TransportURLReconciler) reconcileNormal(ctx context.Context, instance *
 
 	// Create a new secret with the transport URL for this CR
 	secret := r.createTransportURLSecret(instance, string(username), string(password), string(host))
-	_, op, err := oko_secret.CreateOrPatchSecret(ctx, helper, instance, secret)
+	// CreateOrPatchSecret only sets the data on creation, but the content
+	// changes with the spec (or the rabbitmq credentials) so patch it here.
+	// Replacing the data also migrates secrets of older formats.
+	data := secret.Data
+	secretLabels := secret.Labels
+	secretAnnotations := secret.Annotations
+	op, err := controllerutil.CreateOrPatch(ctx, r.Client, secret, func() error {
+		secret.Data = data
+		secret.Labels = util.MergeStringMaps(secret.Labels, secretLabels)
+		secret.Annotations = util.MergeStringMaps(secret.Annotations, secretAnnotations)
+		return controllerutil.SetControllerReference(instance, secret, r.Scheme)
+	})
 	if err != nil {
 		instance.Status.Conditions.Set(condition.FalseCondition(
 			rabbitmqv1beta1.TransportURLReadyCondition,
@@ -231,8 +295,68 @@ func (r *TransportURLReconciler) reconcileNormal(ctx context.Context, instance *
 		return ctrl.Result{RequeueAfter: time.Second * 5}, nil
 	}
 
+	// The operator prefix or suffix changed, remove the secrets named before
+	if instance.Status.SecretName != "" && instance.Status.SecretName != secret.Name {
+		if err := r.deleteRenamedSecrets(ctx, instance, instance.Status.SecretName); err != nil {
+			instance.Status.Conditions.Set(condition.FalseCondition(
+				rabbitmqv1beta1.TransportURLReadyCondition,
+				condition.ErrorReason,
+				condition.SeverityWarning,
+				rabbitmqv1beta1.TransportURLReadyErrorMessage,
+				err.Error()))
+			return ctrl.Result{}, err
+		}
+		instance.Status.SecretName = secret.Name
+		instance.Status.ReplicatedNamespaces = nil
+	}
+
+	// Replicate the secret into the target namespaces, owner references can't
+	// cross namespaces so a finalizer takes care of the copies. The namespace of
+	// the CR already has the secret.
+	targets := []string{}
+	for _, ns := range instance.Spec.TargetNamespaces {
+		if ns == instance.Namespace {
+			continue
+		}
+		if !replicationAllowed(ns) {
+			instance.Status.Conditions.Set(condition.FalseCondition(
+				rabbitmqv1beta1.TransportURLReadyCondition,
+				condition.ErrorReason,
+				condition.SeverityWarning,
+				rabbitmqv1beta1.TransportURLReadyErrorMessage,
+				fmt.Sprintf("replicating the secret to namespace %s is not allowed by the operator", ns)))
+			return ctrl.Result{}, nil
+		}
+		targets = append(targets, ns)
+	}
+	if len(targets) > 0 && controllerutil.AddFinalizer(instance, helper.GetFinalizer()) {
+		if err := r.Update(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	for _, ns := range targets {
+		if err := r.replicateSecret(ctx, instance, secret, ns); err != nil {
+			instance.Status.Conditions.Set(condition.FalseCondition(
+				rabbitmqv1beta1.TransportURLReadyCondition,
+				condition.ErrorReason,
+				condition.SeverityWarning,
+				rabbitmqv1beta1.TransportURLReadyErrorMessage,
+				err.Error()))
+			return ctrl.Result{}, err
+		}
+	}
+	for _, ns := range instance.Status.ReplicatedNamespaces {
+		if !util.StringInSlice(ns, targets) {
+			if err := r.deleteReplicatedSecret(ctx, instance, secret.Name, ns); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+	instance.Status.ReplicatedNamespaces = targets
+
 	// Update the CR and return
 	instance.Status.SecretName = secret.Name
+	instance.Status.SecretFormat = rabbitmqv1beta1.TransportURLSecretFormat
 
 	instance.Status.Conditions.MarkTrue(rabbitmqv1beta1.TransportURLReadyCondition, rabbitmqv1beta1.TransportURLReadyMessage)
 
@@ -242,16 +366,153 @@ func (r *TransportURLReconciler) reconcileNormal(ctx context.Context, instance *
 
 // Create k8s secret with transport URL
 func (r *TransportURLReconciler) createTransportURLSecret(instance *rabbitmqv1beta1.TransportURL, username string, password string, host string) *corev1.Secret {
+	data := map[string][]byte{
+		rabbitmqv1beta1.TransportURLSecretFormatKey:   []byte(rabbitmqv1beta1.TransportURLSecretFormat),
+		rabbitmqv1beta1.TransportURLSecretURLKey:      []byte(fmt.Sprintf("rabbit://%s:%s@%s:%d", username, password, host, rabbitmqPort)),
+		rabbitmqv1beta1.TransportURLSecretUsernameKey: []byte(username),
+		rabbitmqv1beta1.TransportURLSecretPasswordKey: []byte(password),
+		rabbitmqv1beta1.TransportURLSecretHostKey:     []byte(host),
+		rabbitmqv1beta1.TransportURLSecretPortKey:     []byte(fmt.Sprint(rabbitmqPort)),
+	}
+	if messagingConf := renderMessagingOptions(instance.Spec.MessagingOptions); messagingConf != "" {
+		data[rabbitmqv1beta1.TransportURLSecretMessagingConfKey] = []byte(messagingConf)
+	}
+
 	// Create a new secret with the transport URL for this CR
-	return &corev1.Secret{
+	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "rabbitmq-transport-url-" + instance.Name,
-			Namespace: instance.Namespace,
+			Name:        transportURLSecretName(instance),
+			Namespace:   instance.Namespace,
+			Labels:      inherit.Labels(instance, instance.Spec.InheritMetadataPrefixes),
+			Annotations: inherit.Annotations(instance, instance.Spec.InheritMetadataPrefixes),
 		},
-		Data: map[string][]byte{
-			"transport_url": []byte(fmt.Sprintf("rabbit://%s:%s@%s:5672", username, password, host)),
+		Data: data,
+	}
+	// a forced reconcile updates the secret and its copies, which makes the
+	// consumers watching them reconcile as well
+	inputhash.SetForceReconcile(instance, secret)
+	return secret
+}
+
+// transportURLSecretName returns the name of the transport URL secret of instance
+func transportURLSecretName(instance *rabbitmqv1beta1.TransportURL) string {
+	return naming.Name("rabbitmq-transport-url-" + instance.Name)
+}
+
+// deleteRenamedSecrets deletes the transport URL secret of instance named
+// name, and its copies, after the secret got renamed
+func (r *TransportURLReconciler) deleteRenamedSecrets(ctx context.Context, instance *rabbitmqv1beta1.TransportURL, name string) error {
+	for _, ns := range instance.Status.ReplicatedNamespaces {
+		if err := r.deleteReplicatedSecret(ctx, instance, name, ns); err != nil {
+			return err
+		}
+	}
+
+	primary := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: instance.Namespace}, primary)
+	if k8s_errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(primary, instance) {
+		// not ours, leave it alone
+		return nil
+	}
+	if err := r.Delete(ctx, primary); err != nil && !k8s_errors.IsNotFound(err) {
+		return fmt.Errorf("error deleting secret %s: %w", name, err)
+	}
+	return nil
+}
+
+// replicateSecret creates or updates a copy of the transport URL secret in namespace
+func (r *TransportURLReconciler) replicateSecret(ctx context.Context, instance *rabbitmqv1beta1.TransportURL, secret *corev1.Secret, namespace string) error {
+	replica := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secret.Name,
+			Namespace: namespace,
 		},
 	}
+	_, err := controllerutil.CreateOrPatch(ctx, r.Client, replica, func() error {
+		if !isReplica(replica, instance) {
+			return fmt.Errorf("secret %s exists in namespace %s and is not a copy of the transport URL secret", replica.Name, namespace)
+		}
+		replica.Data = secret.Data
+		replica.Labels = util.MergeStringMaps(replica.Labels, secret.Labels, replicaLabels(instance))
+		replica.Annotations = util.MergeStringMaps(replica.Annotations, secret.Annotations)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error replicating secret %s to namespace %s: %w", secret.Name, namespace, err)
+	}
+
+	return nil
+}
+
+// deleteReplicatedSecret deletes the copy named name of the transport URL
+// secret in namespace, if it was created for instance
+func (r *TransportURLReconciler) deleteReplicatedSecret(ctx context.Context, instance *rabbitmqv1beta1.TransportURL, name string, namespace string) error {
+	if namespace == instance.Namespace {
+		// the primary secret, garbage collected via the owner reference
+		return nil
+	}
+	replica := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, replica)
+	if k8s_errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !isReplica(replica, instance) {
+		// not ours, leave it alone
+		return nil
+	}
+
+	if err := r.Delete(ctx, replica); err != nil && !k8s_errors.IsNotFound(err) {
+		return fmt.Errorf("error deleting replicated secret %s in namespace %s: %w", replica.Name, namespace, err)
+	}
+	return nil
+}
+
+// isReplica returns true if secret is a copy of the transport URL secret of
+// instance, or does not exist yet
+func isReplica(secret *corev1.Secret, instance *rabbitmqv1beta1.TransportURL) bool {
+	if secret.ResourceVersion == "" {
+		return true
+	}
+	for k, v := range replicaLabels(instance) {
+		if secret.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// replicaLabels returns the labels identifying the secret copies of instance
+func replicaLabels(instance *rabbitmqv1beta1.TransportURL) map[string]string {
+	return labels.GetLabels(instance, labels.GetGroupLabel("transporturl"), map[string]string{})
+}
+
+// renderMessagingOptions returns an oslo.messaging config snippet with the
+// options set in opts, or an empty string if none is set
+func renderMessagingOptions(opts rabbitmqv1beta1.MessagingOptions) string {
+	options := []string{}
+	if opts.HeartbeatTimeoutThreshold != nil {
+		options = append(options, fmt.Sprintf("heartbeat_timeout_threshold=%d", *opts.HeartbeatTimeoutThreshold))
+	}
+	if opts.RabbitRetryInterval != nil {
+		options = append(options, fmt.Sprintf("rabbit_retry_interval=%d", *opts.RabbitRetryInterval))
+	}
+	if opts.KombuReconnectDelay != "" {
+		options = append(options, fmt.Sprintf("kombu_reconnect_delay=%s", opts.KombuReconnectDelay))
+	}
+	if len(options) == 0 {
+		return ""
+	}
+
+	return "[oslo_messaging_rabbit]\n" + strings.Join(options, "\n") + "\n"
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -259,7 +520,7 @@ func (r *TransportURLReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&rabbitmqv1beta1.TransportURL{}).
 		Owns(&corev1.Secret{}).
-		Complete(r)
+		Complete(health.Wrap("TransportURL", r))
 }
 
 // GetRabbitmqCluster - get RabbitmqCluster object in namespace
@@ -9,6 +9,7 @@ require (
 	github.com/openstack-k8s-operators/infra-operator/apis v0.0.0-20230126021131-f8f8e3c5ad1e
 	github.com/openstack-k8s-operators/keystone-operator/api v0.0.0-20230120095729-d9c56b54cc8d
 	github.com/openstack-k8s-operators/lib-common/modules/common v0.0.0-20230208113903-f7b52e2a2ccb
+	github.com/prometheus/client_golang v1.14.0
 	github.com/rabbitmq/cluster-operator v1.14.0
 	k8s.io/api v0.26.1
 	k8s.io/apimachinery v0.26.1
@@ -46,7 +47,6 @@ require (
 	github.com/openshift/api v3.9.0+incompatible // indirect
 	github.com/openstack-k8s-operators/lib-common/modules/openstack v0.0.0-20220915080953-f73a201a1da6 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
-	github.com/prometheus/client_golang v1.14.0 // indirect
 	github.com/prometheus/client_model v0.3.0 // indirect
 	github.com/prometheus/common v0.37.0 // indirect
 	github.com/prometheus/procfs v0.8.0 // indirect
@@ -19,25 +19,36 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	clientv1beta1 "github.com/openstack-k8s-operators/infra-operator/apis/client/v1beta1"
+	discoveryv1 "github.com/openstack-k8s-operators/infra-operator/apis/discovery/v1beta1"
 	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
 	rabbitmqv1beta1 "github.com/openstack-k8s-operators/infra-operator/apis/rabbitmq/v1beta1"
 	clientcontrollers "github.com/openstack-k8s-operators/infra-operator/controllers/client"
+	discoverycontrollers "github.com/openstack-k8s-operators/infra-operator/controllers/discovery"
 	memcachedcontrollers "github.com/openstack-k8s-operators/infra-operator/controllers/memcached"
 	rabbitmqcontrollers "github.com/openstack-k8s-operators/infra-operator/controllers/rabbitmq"
+	"github.com/openstack-k8s-operators/infra-operator/pkg/cachetransform"
+	"github.com/openstack-k8s-operators/infra-operator/pkg/health"
+	"github.com/openstack-k8s-operators/infra-operator/pkg/orphan"
 	keystonev1 "github.com/openstack-k8s-operators/keystone-operator/api/v1beta1"
+	labels "github.com/openstack-k8s-operators/lib-common/modules/common/labels"
 	rabbitmqclusterv1 "github.com/rabbitmq/cluster-operator/api/v1beta1"
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
@@ -57,6 +68,7 @@ func init() {
 	utilruntime.Must(clientv1beta1.AddToScheme(scheme))
 	utilruntime.Must(keystonev1.AddToScheme(scheme))
 	utilruntime.Must(memcachedv1.AddToScheme(scheme))
+	utilruntime.Must(discoveryv1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -64,11 +76,20 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var reconcileStuckAfter time.Duration
+	var orphanAuditInterval time.Duration
+	var reconcileDeadline time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&reconcileStuckAfter, "reconcile-stuck-after", 10*time.Minute,
+		"Duration after which a still running reconcile fails the healthz check of its controller.")
+	flag.DurationVar(&reconcileDeadline, "reconcile-deadline", 2*time.Minute,
+		"Deadline of the context of each reconcile, reconciles getting close to it are reported. 0 disables it.")
+	flag.DurationVar(&orphanAuditInterval, "orphan-audit-interval", 10*time.Minute,
+		"Interval between audits for operator created objects without an owning CR.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -84,6 +105,7 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "c8c223a1.openstack.org",
+		NewCache:               cache.BuilderWithOptions(cachetransform.Options()),
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -112,6 +134,9 @@ func main() {
 		os.Exit(1)
 	}
 
+	health.Controllers.Deadline = reconcileDeadline
+	health.Controllers.Recorder = mgr.GetEventRecorderFor("infra-operator")
+
 	if err = (&rabbitmqcontrollers.TransportURLReconciler{
 		Client:  mgr.GetClient(),
 		Scheme:  mgr.GetScheme(),
@@ -139,8 +164,46 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Memcached")
 		os.Exit(1)
 	}
+	if err = (&discoverycontrollers.ServiceEndpointsReconciler{
+		Client:  mgr.GetClient(),
+		Kclient: kclient,
+		Log:     ctrl.Log.WithName("controllers").WithName("ServiceEndpoints"),
+		Scheme:  mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ServiceEndpoints")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
+	// adopt or report objects left behind by interrupted deletes
+	if err := mgr.Add(&orphan.Auditor{
+		Client:   mgr.GetClient(),
+		Reader:   mgr.GetAPIReader(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("orphan-audit"),
+		Log:      ctrl.Log.WithName("orphan-audit"),
+		Interval: orphanAuditInterval,
+		Rules: []orphan.Rule{{
+			Kind:      "MemcachedService",
+			List:      func() client.ObjectList { return &corev1.ServiceList{} },
+			NameLabel: labels.GetOwnerNameLabelSelector("memcached"),
+			Owner:     func() client.Object { return &memcachedv1.Memcached{} },
+		}, {
+			Kind:      "MemcachedStatefulSet",
+			List:      func() client.ObjectList { return &appsv1.StatefulSetList{} },
+			NameLabel: labels.GetOwnerNameLabelSelector("memcached"),
+			Owner:     func() client.Object { return &memcachedv1.Memcached{} },
+		}, {
+			Kind:      "OpenStackClientConfigMap",
+			List:      func() client.ObjectList { return &corev1.ConfigMapList{} },
+			NameLabel: labels.GetOwnerNameLabelSelector(labels.GetGroupLabel("openstackclient")),
+			Owner:     func() client.Object { return &clientv1beta1.OpenStackClient{} },
+		}},
+	}); err != nil {
+		setupLog.Error(err, "unable to set up orphan audit")
+		os.Exit(1)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -149,6 +212,21 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if err := mgr.AddReadyzCheck("informers", health.InformerSyncCheck(mgr.GetCache())); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+	for _, c := range []string{"TransportURL", "OpenStackClient", "Memcached", "ServiceEndpoints"} {
+		if err := mgr.AddHealthzCheck(c, health.Controllers.Checker(c, reconcileStuckAfter)); err != nil {
+			setupLog.Error(err, "unable to set up health check", "controller", c)
+			os.Exit(1)
+		}
+	}
+	// per controller reconcile details, served next to the metrics
+	if err := mgr.AddMetricsExtraHandler("/debug/controllers", health.Controllers); err != nil {
+		setupLog.Error(err, "unable to set up controller diagnostics endpoint")
+		os.Exit(1)
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -0,0 +1,71 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cachetransform
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// lastAppliedAnnotation - set by kubectl apply, holds a full copy of the
+// object and is never read by the controllers
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Options returns the cache options stripping fields the controllers never
+// read from the cached objects. On clusters with thousands of Secrets and
+// ConfigMaps the managedFields are often larger than the data itself.
+//
+// Only metadata not written by the controllers is dropped, so patches
+// computed from cached objects stay the same.
+func Options() cache.Options {
+	return cache.Options{
+		DefaultTransform: StripManagedFields,
+		TransformByObject: cache.TransformByObject{
+			&corev1.Secret{}:      StripMetadata,
+			&corev1.ConfigMap{}:   StripMetadata,
+			&appsv1.StatefulSet{}: StripMetadata,
+			&corev1.Service{}:     StripMetadata,
+		},
+	}
+}
+
+// StripManagedFields - removes the managedFields of the object
+func StripManagedFields(in interface{}) (interface{}, error) {
+	if obj, ok := in.(client.Object); ok {
+		obj.SetManagedFields(nil)
+	}
+	return in, nil
+}
+
+// StripMetadata - removes the managedFields and the last applied
+// configuration annotation of the object
+func StripMetadata(in interface{}) (interface{}, error) {
+	obj, ok := in.(client.Object)
+	if !ok {
+		return in, nil
+	}
+	obj.SetManagedFields(nil)
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		if _, ok := annotations[lastAppliedAnnotation]; ok {
+			delete(annotations, lastAppliedAnnotation)
+			obj.SetAnnotations(annotations)
+		}
+	}
+	return obj, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cachetransform
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStripMetadata(t *testing.T) {
+	managedFields := []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}
+
+	tests := []struct {
+		name            string
+		annotations     map[string]string
+		wantAnnotations map[string]string
+	}{
+		{
+			name: "no annotations",
+		},
+		{
+			name:            "other annotations are kept",
+			annotations:     map[string]string{"a": "b"},
+			wantAnnotations: map[string]string{"a": "b"},
+		},
+		{
+			name:            "last applied configuration is dropped",
+			annotations:     map[string]string{"a": "b", lastAppliedAnnotation: "{}"},
+			wantAnnotations: map[string]string{"a": "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			in := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:          "secret",
+					Annotations:   tt.annotations,
+					ManagedFields: managedFields,
+				},
+				Data: map[string][]byte{"key": []byte("value")},
+			}
+
+			out, err := StripMetadata(in)
+			g.Expect(err).NotTo(HaveOccurred())
+			secret := out.(*corev1.Secret)
+			g.Expect(secret.ManagedFields).To(BeNil())
+			g.Expect(secret.Annotations).To(Equal(tt.wantAnnotations))
+			g.Expect(secret.Data).To(HaveKeyWithValue("key", []byte("value")))
+		})
+	}
+}
+
+func TestStripMetadataIgnoresOtherTypes(t *testing.T) {
+	g := NewWithT(t)
+	out, err := StripMetadata("not an object")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(out).To(Equal("not an object"))
+}
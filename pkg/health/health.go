@@ -0,0 +1,224 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// deadlineWarnRatio - share of the deadline after which a reconcile counts as slow
+const deadlineWarnRatio = 0.8
+
+var (
+	reconcileSlow = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "infra_operator_reconcile_slow_total",
+			Help: "Reconciles which took more than 80% of the reconcile deadline, by controller",
+		},
+		[]string{"controller"},
+	)
+	reconcileDeadlineExceeded = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "infra_operator_reconcile_deadline_exceeded_total",
+			Help: "Reconciles which exceeded the reconcile deadline, by controller",
+		},
+		[]string{"controller"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(reconcileSlow, reconcileDeadlineExceeded)
+}
+
+// ControllerStatus - reconcile activity of a single controller
+type ControllerStatus struct {
+	// LastSuccess - time the last reconcile without error finished
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	// LastError - error returned by the last failed reconcile
+	LastError string `json:"lastError,omitempty"`
+	// LastErrorTime - time the last failed reconcile finished
+	LastErrorTime *time.Time `json:"lastErrorTime,omitempty"`
+	// InFlight - start time of the reconciles currently running, by request
+	InFlight map[string]time.Time `json:"inFlight,omitempty"`
+}
+
+// Tracker records the reconcile activity of the controllers of the manager
+// and serves it as JSON for diagnostics
+type Tracker struct {
+	mu          sync.Mutex
+	controllers map[string]*ControllerStatus
+
+	// Deadline - if set, the context of each reconcile is cancelled after it
+	Deadline time.Duration
+	// Recorder - if set, slow reconciles are reported as Events on the reconciled CR
+	Recorder record.EventRecorder
+}
+
+// Controllers - tracker used by the reconcilers wrapped with Wrap
+var Controllers = NewTracker()
+
+// NewTracker returns an empty Tracker
+func NewTracker() *Tracker {
+	return &Tracker{
+		controllers: map[string]*ControllerStatus{},
+	}
+}
+
+func (t *Tracker) get(name string) *ControllerStatus {
+	s, ok := t.controllers[name]
+	if !ok {
+		s = &ControllerStatus{InFlight: map[string]time.Time{}}
+		t.controllers[name] = s
+	}
+	return s
+}
+
+func (t *Tracker) start(name string, req string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.get(name).InFlight[req] = time.Now()
+}
+
+func (t *Tracker) finish(name string, req string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.get(name)
+	delete(s.InFlight, req)
+	now := time.Now()
+	if err != nil {
+		s.LastError = err.Error()
+		s.LastErrorTime = &now
+		return
+	}
+	s.LastSuccess = &now
+}
+
+// Checker returns a healthz check failing if a reconcile of the named
+// controller has been running for longer than stuckAfter
+func (t *Tracker) Checker(name string, stuckAfter time.Duration) healthz.Checker {
+	return func(_ *http.Request) error {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for req, started := range t.get(name).InFlight {
+			if since := time.Since(started); since > stuckAfter {
+				return fmt.Errorf("reconcile of %s running for %s", req, since.Round(time.Second))
+			}
+		}
+		return nil
+	}
+}
+
+// ServeHTTP serves the status of all tracked controllers as JSON
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	t.mu.Lock()
+	data, err := json.MarshalIndent(t.controllers, "", "  ")
+	t.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// statusContextKey - key of the context a reconcile got before the deadline applied
+type statusContextKey struct{}
+
+// StatusContext returns the context to persist the status of a reconcile with.
+// Unlike ctx it is not cancelled by the reconcile deadline, so the deferred
+// status patch still records why a reconcile ran out of time.
+func StatusContext(ctx context.Context) context.Context {
+	if parent, ok := ctx.Value(statusContextKey{}).(context.Context); ok {
+		return parent
+	}
+	return ctx
+}
+
+type trackedReconciler struct {
+	name    string
+	tracker *Tracker
+	reconcile.Reconciler
+}
+
+// Reconcile - records the reconcile in the tracker and calls the wrapped
+// reconciler, bounded by the deadline of the tracker
+func (r *trackedReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	deadline := r.tracker.Deadline
+	if deadline > 0 {
+		var cancel context.CancelFunc
+		parent := ctx
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+		ctx = context.WithValue(ctx, statusContextKey{}, parent)
+		defer cancel()
+	}
+
+	r.tracker.start(r.name, req.String())
+	started := time.Now()
+	result, err := r.Reconciler.Reconcile(ctx, req)
+	r.tracker.finish(r.name, req.String(), err)
+
+	if elapsed := time.Since(started); deadline > 0 && elapsed > time.Duration(float64(deadline)*deadlineWarnRatio) {
+		reason, counter := "ReconcileSlow", reconcileSlow
+		if elapsed >= deadline {
+			reason, counter = "ReconcileDeadlineExceeded", reconcileDeadlineExceeded
+		}
+		counter.WithLabelValues(r.name).Inc()
+		if r.tracker.Recorder != nil {
+			ref := &corev1.ObjectReference{Kind: r.name, Namespace: req.Namespace, Name: req.Name}
+			r.tracker.Recorder.Eventf(ref, corev1.EventTypeWarning, reason,
+				"Reconcile took %s of the %s deadline", elapsed.Round(time.Millisecond), deadline)
+		}
+	}
+
+	return result, err
+}
+
+// Wrap returns a reconciler recording the activity of r under name in Controllers
+func Wrap(name string, r reconcile.Reconciler) reconcile.Reconciler {
+	return &trackedReconciler{
+		name:       name,
+		tracker:    Controllers,
+		Reconciler: r,
+	}
+}
+
+// InformerSyncCheck returns a readyz check failing until the informer
+// caches of the manager are synced
+func InformerSyncCheck(c cache.Cache) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), time.Second)
+		defer cancel()
+		if !c.WaitForCacheSync(ctx) {
+			return errors.New("informer caches not synced")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagepolicy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// RequireDigestEnv - operator env var, if "true" all images have to be pinned by digest
+	RequireDigestEnv = "IMAGE_REQUIRE_DIGEST"
+
+	// AllowedRegistriesEnv - operator env var, comma separated list of registry
+	// prefixes all images have to be pulled from
+	AllowedRegistriesEnv = "IMAGE_ALLOWED_REGISTRIES"
+)
+
+// Policy - checks a container image reference has to pass before it gets deployed
+type Policy struct {
+	// RequireDigest - the image reference has to be pinned by digest
+	RequireDigest bool
+	// AllowedRegistries - if not empty, the image reference has to start with one
+	// of these prefixes, e.g. "quay.io" or "registry.example.com/openstack"
+	AllowedRegistries []string
+}
+
+// OperatorPolicy returns the operator wide policy configured via the environment
+func OperatorPolicy() Policy {
+	p := Policy{
+		RequireDigest: os.Getenv(RequireDigestEnv) == "true",
+	}
+	for _, r := range strings.Split(os.Getenv(AllowedRegistriesEnv), ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			p.AllowedRegistries = append(p.AllowedRegistries, r)
+		}
+	}
+
+	return p
+}
+
+// Verify returns an error if image does not satisfy the policy
+func (p Policy) Verify(image string) error {
+	if p.RequireDigest && !strings.Contains(image, "@sha256:") {
+		return fmt.Errorf("image %s is not pinned by digest", image)
+	}
+
+	if len(p.AllowedRegistries) == 0 {
+		return nil
+	}
+	for _, r := range p.AllowedRegistries {
+		r = strings.TrimSuffix(r, "/")
+		if strings.HasPrefix(image, r+"/") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("image %s is not from an allowed registry %v", image, p.AllowedRegistries)
+}
+
+// VerifyPodSpec verifies the images of all (init) containers of the pod spec
+// against all given policies
+func VerifyPodSpec(spec corev1.PodSpec, policies ...Policy) error {
+	containers := append([]corev1.Container{}, spec.InitContainers...)
+	containers = append(containers, spec.Containers...)
+	for _, c := range containers {
+		for _, p := range policies {
+			if err := p.Verify(c.Image); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
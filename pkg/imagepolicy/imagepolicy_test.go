@@ -0,0 +1,95 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagepolicy
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestVerify(t *testing.T) {
+	const digest = "@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name    string
+		policy  Policy
+		image   string
+		wantErr bool
+	}{
+		{
+			name:  "empty policy allows any image",
+			image: "memcached:latest",
+		},
+		{
+			name:   "digest required and present",
+			policy: Policy{RequireDigest: true},
+			image:  "quay.io/podified/memcached" + digest,
+		},
+		{
+			name:    "digest required but tagged",
+			policy:  Policy{RequireDigest: true},
+			image:   "quay.io/podified/memcached:current",
+			wantErr: true,
+		},
+		{
+			name:   "allowed registry",
+			policy: Policy{AllowedRegistries: []string{"registry.example.com", "quay.io"}},
+			image:  "quay.io/podified/memcached:current",
+		},
+		{
+			name:   "allowed registry with trailing slash",
+			policy: Policy{AllowedRegistries: []string{"quay.io/"}},
+			image:  "quay.io/podified/memcached:current",
+		},
+		{
+			name:   "allowed repository prefix",
+			policy: Policy{AllowedRegistries: []string{"registry.example.com/openstack"}},
+			image:  "registry.example.com/openstack/memcached:current",
+		},
+		{
+			name:    "registry not allowed",
+			policy:  Policy{AllowedRegistries: []string{"registry.example.com"}},
+			image:   "quay.io/podified/memcached:current",
+			wantErr: true,
+		},
+		{
+			name:    "registry prefix must end at a path segment",
+			policy:  Policy{AllowedRegistries: []string{"quay.io"}},
+			image:   "quay.io.evil.com/podified/memcached:current",
+			wantErr: true,
+		},
+		{
+			name:    "both checks apply",
+			policy:  Policy{RequireDigest: true, AllowedRegistries: []string{"quay.io"}},
+			image:   "registry.example.com/memcached" + digest,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := tt.policy.Verify(tt.image)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
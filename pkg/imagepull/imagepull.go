@@ -0,0 +1,93 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagepull
+
+import (
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// PullSecretsEnv - operator env var, comma separated list of secrets used
+	// to pull the images of all generated pods
+	PullSecretsEnv = "IMAGE_PULL_SECRETS"
+
+	// PullPolicyEnv - operator env var, default pull policy of all generated containers
+	PullPolicyEnv = "IMAGE_PULL_POLICY"
+)
+
+// Settings - how the images of a pod get pulled
+type Settings struct {
+	// PullSecrets - secrets used to pull the images
+	PullSecrets []corev1.LocalObjectReference
+	// PullPolicy - pull policy of the containers, if empty the cluster default applies
+	PullPolicy corev1.PullPolicy
+}
+
+// OperatorDefaults returns the operator wide settings configured via the environment
+func OperatorDefaults() Settings {
+	s := Settings{
+		PullPolicy: corev1.PullPolicy(os.Getenv(PullPolicyEnv)),
+	}
+	for _, name := range strings.Split(os.Getenv(PullSecretsEnv), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			s.PullSecrets = append(s.PullSecrets, corev1.LocalObjectReference{Name: name})
+		}
+	}
+
+	return s
+}
+
+// Apply adds the pull secrets of the operator defaults and of the CR to the pod
+// spec, and sets the pull policy on all (init) containers not having one. The
+// pull policy of the CR takes precedence over the operator default.
+func Apply(spec *corev1.PodSpec, defaults Settings, cr Settings) {
+	for _, s := range append(defaults.PullSecrets, cr.PullSecrets...) {
+		if !hasSecret(spec.ImagePullSecrets, s.Name) {
+			spec.ImagePullSecrets = append(spec.ImagePullSecrets, s)
+		}
+	}
+
+	policy := defaults.PullPolicy
+	if cr.PullPolicy != "" {
+		policy = cr.PullPolicy
+	}
+	if policy == "" {
+		return
+	}
+	for i := range spec.InitContainers {
+		if spec.InitContainers[i].ImagePullPolicy == "" {
+			spec.InitContainers[i].ImagePullPolicy = policy
+		}
+	}
+	for i := range spec.Containers {
+		if spec.Containers[i].ImagePullPolicy == "" {
+			spec.Containers[i].ImagePullPolicy = policy
+		}
+	}
+}
+
+func hasSecret(secrets []corev1.LocalObjectReference, name string) bool {
+	for _, s := range secrets {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inherit
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Labels returns the labels of obj whose key starts with one of prefixes
+func Labels(obj metav1.Object, prefixes []string) map[string]string {
+	return filter(obj.GetLabels(), prefixes)
+}
+
+// Annotations returns the annotations of obj whose key starts with one of prefixes
+func Annotations(obj metav1.Object, prefixes []string) map[string]string {
+	return filter(obj.GetAnnotations(), prefixes)
+}
+
+func filter(m map[string]string, prefixes []string) map[string]string {
+	filtered := map[string]string{}
+	for k, v := range m {
+		for _, prefix := range prefixes {
+			if prefix != "" && strings.HasPrefix(k, prefix) {
+				filtered[k] = v
+				break
+			}
+		}
+	}
+
+	return filtered
+}
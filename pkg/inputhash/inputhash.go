@@ -0,0 +1,93 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inputhash
+
+import (
+	env "github.com/openstack-k8s-operators/lib-common/modules/common/env"
+	util "github.com/openstack-k8s-operators/lib-common/modules/common/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HashName - key of the combined input hash in the status hash map
+	HashName = "input"
+
+	// EnvName - name of the env var carrying the combined input hash on pod
+	// templates, so that a change of any input rolls the pods
+	EnvName = "CONFIG_HASH"
+
+	// ForceReconcileAnnotation - annotation on a CR whose value is folded into
+	// the input hash, changing it forces all hashed artifacts to be regenerated.
+	// Memcached rolls its pods, TransportURL updates its secrets and
+	// OpenStackClient recreates its pod.
+	ForceReconcileAnnotation = "infra.openstack.org/force-reconcile"
+)
+
+// Create returns the combined hash of all inputs in envVars (as collected by
+// EnsureConfigMaps/EnsureSecrets), stores it in hashMap and reports whether it
+// changed compared to the value stored before.
+func Create(
+	hashMap map[string]string,
+	envVars map[string]env.Setter,
+) (string, map[string]string, bool, error) {
+	mergedMapVars := env.MergeEnvs([]corev1.EnvVar{}, envVars)
+	hash, err := util.ObjectHash(mergedMapVars)
+	if err != nil {
+		return hash, hashMap, false, err
+	}
+
+	hashMap, changed := util.SetHash(hashMap, HashName, hash)
+	return hash, hashMap, changed, nil
+}
+
+// EnvVar returns the env var to add to the pod template for the input hash
+func EnvVar(hash string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name:  EnvName,
+		Value: hash,
+	}
+}
+
+// SetForceReconcile records the value of the force-reconcile annotation of obj,
+// if set, on the generated object, for controllers without an input hash
+func SetForceReconcile(obj metav1.Object, generated metav1.Object) {
+	nonce, ok := obj.GetAnnotations()[ForceReconcileAnnotation]
+	if !ok {
+		return
+	}
+	annotations := generated.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[ForceReconcileAnnotation] = nonce
+	generated.SetAnnotations(annotations)
+}
+
+// ForceReconcileChanged reports whether the force-reconcile annotation of obj
+// differs from the value recorded on the generated object
+func ForceReconcileChanged(obj metav1.Object, generated metav1.Object) bool {
+	return obj.GetAnnotations()[ForceReconcileAnnotation] != generated.GetAnnotations()[ForceReconcileAnnotation]
+}
+
+// AddForceReconcile adds the value of the force-reconcile annotation of obj,
+// if set, as an input to envVars
+func AddForceReconcile(obj metav1.Object, envVars map[string]env.Setter) {
+	if nonce, ok := obj.GetAnnotations()[ForceReconcileAnnotation]; ok {
+		envVars[ForceReconcileAnnotation] = env.SetValue(nonce)
+	}
+}
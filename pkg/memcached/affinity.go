@@ -0,0 +1,45 @@
+package memcached
+
+import (
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	affinity "github.com/openstack-k8s-operators/lib-common/modules/common/affinity"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// podAffinity returns the affinity spreading the replicas of the CR, selected
+// by podLabels, across nodes, preferably or required by the spec
+func podAffinity(m *memcachedv1.Memcached, podLabels map[string]string) *corev1.Affinity {
+	podsAffinity := affinity.DistributePods("cr", []string{podLabels["cr"]}, corev1.LabelHostname)
+	if m.Spec.RequireAntiAffinity {
+		podsAffinity.PodAntiAffinity = &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: podLabels,
+				},
+				TopologyKey: corev1.LabelHostname,
+			}},
+		}
+	}
+	podsAffinity.NodeAffinity = archNodeAffinity(m)
+
+	return podsAffinity
+}
+
+// topologySpreadConstraints returns the constraints evenly spreading the replicas
+// selected by podLabels across nodes and zones where possible
+func topologySpreadConstraints(podLabels map[string]string) []corev1.TopologySpreadConstraint {
+	constraints := []corev1.TopologySpreadConstraint{}
+	for _, key := range []string{corev1.LabelHostname, corev1.LabelTopologyZone} {
+		constraints = append(constraints, corev1.TopologySpreadConstraint{
+			MaxSkew:           1,
+			TopologyKey:       key,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: podLabels,
+			},
+		})
+	}
+
+	return constraints
+}
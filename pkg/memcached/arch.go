@@ -0,0 +1,48 @@
+package memcached
+
+import (
+	"fmt"
+
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Image returns the memcached container image for the architecture of the CR,
+// ValidateArchitecture makes sure there is one if architectureImages is set
+func Image(m *memcachedv1.Memcached) string {
+	if m.Spec.Architecture != "" && len(m.Spec.ArchitectureImages) > 0 {
+		return m.Spec.ArchitectureImages[m.Spec.Architecture]
+	}
+	return m.Spec.ContainerImage
+}
+
+// ValidateArchitecture returns an error if architectureImages lacks an image
+// for the selected architecture, instead of running containerImage on it
+func ValidateArchitecture(m *memcachedv1.Memcached) error {
+	if m.Spec.Architecture == "" || len(m.Spec.ArchitectureImages) == 0 {
+		return nil
+	}
+	if m.Spec.ArchitectureImages[m.Spec.Architecture] == "" {
+		return fmt.Errorf("architectureImages has no image for architecture %s", m.Spec.Architecture)
+	}
+	return nil
+}
+
+// archNodeAffinity returns the node affinity pinning the pods to nodes of the
+// architecture of the CR, or nil if no architecture is selected
+func archNodeAffinity(m *memcachedv1.Memcached) *corev1.NodeAffinity {
+	if m.Spec.Architecture == "" {
+		return nil
+	}
+	return &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{{
+					Key:      corev1.LabelArchStable,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{m.Spec.Architecture},
+				}},
+			}},
+		},
+	}
+}
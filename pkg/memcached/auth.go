@@ -0,0 +1,127 @@
+package memcached
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	inherit "github.com/openstack-k8s-operators/infra-operator/pkg/inherit"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SASLConfigKey - key of the SASL config in the config secret
+	SASLConfigKey = "memcached-sasl.conf"
+
+	// saslConfigPath - directory of the SASL config of the memcached application
+	saslConfigPath = "/etc/sasl2"
+
+	// saslDBPath - directory of the SASL password database
+	saslDBPath = "/var/lib/sasl"
+)
+
+// SASLConfig returns the SASL config of memcached, checking the plain text
+// credentials against the database the init container creates
+func SASLConfig() string {
+	return fmt.Sprintf("mech_list: plain\nsasldb_path: %s/sasldb2\n", saslDBPath)
+}
+
+// ValidateAuth returns an error if authentication is enabled without credentials
+// or together with features not supporting it
+func ValidateAuth(m *memcachedv1.Memcached) error {
+	if !m.Spec.AuthEnabled {
+		return nil
+	}
+	if m.Spec.AuthSecret == "" {
+		return errors.New("authEnabled requires authSecret")
+	}
+	if m.Spec.Metrics.Enabled {
+		return errors.New("the metrics exporter doesn't support SASL authentication")
+	}
+	return nil
+}
+
+// ConnectionSecret returns the secret with the servers of serverList and the
+// credentials of the auth secret for the clients of the CR
+func ConnectionSecret(m *memcachedv1.Memcached, serverList []string, auth *corev1.Secret) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ConnectionSecretName(m),
+			Namespace:   m.Namespace,
+			Labels:      inherit.Labels(m, m.Spec.InheritMetadataPrefixes),
+			Annotations: inherit.Annotations(m, m.Spec.InheritMetadataPrefixes),
+		},
+		Data: map[string][]byte{
+			memcachedv1.ConnectionServersKey: []byte(strings.Join(serverList, ",")),
+			memcachedv1.AuthUsernameKey:      auth.Data[memcachedv1.AuthUsernameKey],
+			memcachedv1.AuthPasswordKey:      auth.Data[memcachedv1.AuthPasswordKey],
+		},
+	}
+}
+
+// addAuth creates the SASL password database of the pods from the auth secret
+// in an init container, and points memcached to it
+func addAuth(m *memcachedv1.Memcached, sfs *appsv1.StatefulSet) {
+	spec := &sfs.Spec.Template.Spec
+	container := &spec.Containers[0]
+
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: "sasl-db",
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	}, corev1.Volume{
+		Name: "sasl-config",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: ConfigSecretName(m),
+				Items: []corev1.KeyToPath{{
+					Key:  SASLConfigKey,
+					Path: "memcached.conf",
+				}},
+			},
+		},
+	})
+	dbMount := corev1.VolumeMount{
+		MountPath: saslDBPath,
+		Name:      "sasl-db",
+	}
+
+	credential := func(key string) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: m.Spec.AuthSecret},
+				Key:                  key,
+			},
+		}
+	}
+	// The init container shares the memcached security context, chmod instead
+	// of chown so the database is readable by the memcached user whether or
+	// not the pod runs as root.
+	spec.InitContainers = append(spec.InitContainers, corev1.Container{
+		Name:  "sasl-init",
+		Image: container.Image,
+		Command: []string{"/bin/sh", "-c", fmt.Sprintf(
+			"echo -n \"$PASSWORD\" | saslpasswd2 -p -c -a memcached -f %[1]s/sasldb2 \"$USERNAME\" && chmod 0644 %[1]s/sasldb2",
+			saslDBPath)},
+		SecurityContext: container.SecurityContext,
+		Env: []corev1.EnvVar{
+			{Name: "USERNAME", ValueFrom: credential(memcachedv1.AuthUsernameKey)},
+			{Name: "PASSWORD", ValueFrom: credential(memcachedv1.AuthPasswordKey)},
+		},
+		VolumeMounts: []corev1.VolumeMount{dbMount},
+	})
+
+	container.Env = append(container.Env, corev1.EnvVar{
+		Name:  "SASL_CONF_PATH",
+		Value: saslConfigPath,
+	})
+	container.VolumeMounts = append(container.VolumeMounts, dbMount, corev1.VolumeMount{
+		MountPath: saslConfigPath,
+		ReadOnly:  true,
+		Name:      "sasl-config",
+	})
+}
@@ -0,0 +1,32 @@
+package memcached
+
+import (
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+)
+
+// GetClientSettings returns the oslo.cache settings recommended for clients of
+// the first replicas memcached servers of the CR.
+//
+// The oslo.cache default dead_retry of 300s keeps a restarted pod out of use
+// long after it is back. The only server of a single replica is retried soon,
+// skipping it just sends all requests to the backends. With several servers a
+// longer retry avoids hitting the timeout of a failed shard on every request.
+func GetClientSettings(m *memcachedv1.Memcached, replicas int32) *memcachedv1.ClientSettings {
+	if replicas == 0 {
+		return nil
+	}
+
+	settings := &memcachedv1.ClientSettings{
+		DeadRetry:     30,
+		SocketTimeout: "1.0",
+	}
+	if replicas == 1 {
+		settings.DeadRetry = 10
+	}
+	// items stored in extstore are read from disk
+	if m.Spec.Extstore != nil {
+		settings.SocketTimeout = "3.0"
+	}
+
+	return settings
+}
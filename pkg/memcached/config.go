@@ -0,0 +1,121 @@
+package memcached
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	util "github.com/openstack-k8s-operators/lib-common/modules/common/util"
+)
+
+// ConfigTemplateVersion - latest version of the config templates, each version
+// is a sub directory of templates/memcached/config
+const ConfigTemplateVersion = "1"
+
+// GetConfigTemplateVersion returns the config template version to use for the
+// Memcached CR, or an error if the pinned version is not shipped with the operator
+func GetConfigTemplateVersion(m *memcachedv1.Memcached) (string, error) {
+	version := m.Spec.ConfigTemplateVersion
+	if version == "" {
+		return ConfigTemplateVersion, nil
+	}
+	// the version is a directory name, it must not point outside of the templates
+	if strings.ContainsAny(version, `/\`) || strings.Contains(version, "..") {
+		return "", fmt.Errorf("invalid config template version %s", version)
+	}
+
+	dir := filepath.Join(util.GetTemplatesPath(), "memcached", string(util.TemplateTypeConfig), version)
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return "", fmt.Errorf("config template version %s does not exist", version)
+	}
+	return version, nil
+}
+
+// requiredTemplateVars lists for each config template which operator managed
+// parameters a defaultConfigOverwrite of it has to keep referencing
+var requiredTemplateVars = map[string][]string{
+	"config.json": {},
+	"memcached":   {".memcachedPort", ".memcachedOptions"},
+}
+
+// ValidateDefaultConfigOverwrite returns an error if the defaultConfigOverwrite
+// of the Memcached CR references an unknown config template or drops a
+// parameter the operator needs to inject
+func ValidateDefaultConfigOverwrite(m *memcachedv1.Memcached) error {
+	keys := []string{}
+	for k := range m.Spec.DefaultConfigOverwrite {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		vars, ok := requiredTemplateVars[k]
+		if !ok {
+			return fmt.Errorf("defaultConfigOverwrite: unknown config template %s", k)
+		}
+		for _, v := range vars {
+			if !strings.Contains(m.Spec.DefaultConfigOverwrite[k], v) {
+				return fmt.Errorf("defaultConfigOverwrite: %s must reference the template variable %s", k, v)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Options returns the memcached command line options for the tuning and the
+// extstore of the Memcached CR, with a leading space, or an empty string if
+// nothing is set
+func Options(m *memcachedv1.Memcached) string {
+	t := m.Spec.Tuning
+	opts := []string{}
+	if t.HashAlgorithm != "" {
+		opts = append(opts, "hash_algorithm="+t.HashAlgorithm)
+	}
+	if t.SlabReassign {
+		opts = append(opts, "slab_reassign")
+	}
+	if t.SlabAutomove != nil {
+		opts = append(opts, fmt.Sprintf("slab_automove=%d", *t.SlabAutomove))
+	}
+	if m.Spec.Extstore != nil {
+		opts = append(opts, fmt.Sprintf("ext_path=%s/extstore:%dM", ExtstorePath, extstoreSizeMB(m)))
+	}
+
+	options := ""
+	if m.Spec.AuthEnabled {
+		options += " -S"
+	}
+	if t.SlabGrowthFactor != "" {
+		options += " -f " + t.SlabGrowthFactor
+	}
+	if len(opts) > 0 {
+		options += " -o " + strings.Join(opts, ",")
+	}
+
+	return options
+}
+
+// minExtstoreSizeMB - memcached needs room for at least one extstore page
+const minExtstoreSizeMB = 64
+
+// extstoreSizeMB returns the size of the extstore file of the CR in MB
+func extstoreSizeMB(m *memcachedv1.Memcached) int64 {
+	// leave room for the filesystem on the volume
+	return m.Spec.Extstore.Size.Value() / 1024 / 1024 * 9 / 10
+}
+
+// ValidateExtstore returns an error if the extstore of the CR is too small
+func ValidateExtstore(m *memcachedv1.Memcached) error {
+	if m.Spec.Extstore == nil {
+		return nil
+	}
+	if sizeMB := extstoreSizeMB(m); sizeMB < minExtstoreSizeMB {
+		return fmt.Errorf("extstore size %s leaves %dM for the extstore file, at least %dM are needed",
+			m.Spec.Extstore.Size.String(), sizeMB, minExtstoreSizeMB)
+	}
+	return nil
+}
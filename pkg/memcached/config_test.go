@@ -0,0 +1,111 @@
+package memcached
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestOptions(t *testing.T) {
+	automove := int32(1)
+
+	tests := []struct {
+		name     string
+		tuning   memcachedv1.Tuning
+		extstore *memcachedv1.Extstore
+		auth     bool
+		want     string
+	}{
+		{
+			name: "nothing set",
+			want: "",
+		},
+		{
+			name: "auth",
+			auth: true,
+			want: " -S",
+		},
+		{
+			name:   "growth factor",
+			tuning: memcachedv1.Tuning{SlabGrowthFactor: "1.5"},
+			want:   " -f 1.5",
+		},
+		{
+			name: "extended options",
+			tuning: memcachedv1.Tuning{
+				HashAlgorithm: "murmur3",
+				SlabReassign:  true,
+				SlabAutomove:  &automove,
+			},
+			want: " -o hash_algorithm=murmur3,slab_reassign,slab_automove=1",
+		},
+		{
+			name:     "extstore leaves room for the filesystem",
+			extstore: &memcachedv1.Extstore{Size: resource.MustParse("1Gi")},
+			want:     " -o ext_path=/var/lib/memcached/extstore/extstore:921M",
+		},
+		{
+			name:     "all options",
+			auth:     true,
+			tuning:   memcachedv1.Tuning{SlabGrowthFactor: "1.25", SlabReassign: true},
+			extstore: &memcachedv1.Extstore{Size: resource.MustParse("100Mi")},
+			want:     " -S -f 1.25 -o slab_reassign,ext_path=/var/lib/memcached/extstore/extstore:90M",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			m := &memcachedv1.Memcached{}
+			m.Spec.Tuning = tt.tuning
+			m.Spec.Extstore = tt.extstore
+			m.Spec.AuthEnabled = tt.auth
+
+			g.Expect(Options(m)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestValidateExtstore(t *testing.T) {
+	tests := []struct {
+		name     string
+		extstore *memcachedv1.Extstore
+		wantErr  bool
+	}{
+		{
+			name: "no extstore",
+		},
+		{
+			name:     "large enough",
+			extstore: &memcachedv1.Extstore{Size: resource.MustParse("72Mi")},
+		},
+		{
+			name:     "too small after the filesystem share",
+			extstore: &memcachedv1.Extstore{Size: resource.MustParse("70Mi")},
+			wantErr:  true,
+		},
+		{
+			name:     "zero size",
+			extstore: &memcachedv1.Extstore{},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			m := &memcachedv1.Memcached{}
+			m.Spec.Extstore = tt.extstore
+
+			err := ValidateExtstore(m)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
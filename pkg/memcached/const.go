@@ -0,0 +1,9 @@
+package memcached
+
+const (
+	// MemcachedPort - port memcached listens on and the service exposes
+	MemcachedPort = 11211
+
+	// ExtstorePath - directory the extstore volume gets mounted to
+	ExtstorePath = "/var/lib/memcached/extstore"
+)
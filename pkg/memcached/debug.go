@@ -0,0 +1,46 @@
+package memcached
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DebugImageEnv - operator env var, image of the debug sidecar, e.g. a netshoot image
+const DebugImageEnv = "MEMCACHED_DEBUG_IMAGE"
+
+// DebugContainerName - name of the debug sidecar container
+const DebugContainerName = "debug"
+
+// DebugContainer returns the debug sidecar requested by the debug-until annotation
+// of the CR and the time left until it expires, or nil if none is requested, the
+// request expired, or no debug image is configured on the operator
+func DebugContainer(m *memcachedv1.Memcached, now time.Time) (*corev1.Container, time.Duration, error) {
+	value, ok := m.Annotations[memcachedv1.DebugUntilAnnotation]
+	image := os.Getenv(DebugImageEnv)
+	if !ok || image == "" {
+		return nil, 0, nil
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid %s annotation: %w", memcachedv1.DebugUntilAnnotation, err)
+	}
+	left := until.Sub(now)
+	if left <= 0 {
+		return nil, 0, nil
+	}
+
+	return &corev1.Container{
+		Name:    DebugContainerName,
+		Image:   image,
+		Command: []string{"/bin/sh", "-c", "trap 'exit 0' TERM; sleep infinity & wait"},
+		SecurityContext: &corev1.SecurityContext{
+			Capabilities: &corev1.Capabilities{
+				Add: []corev1.Capability{"NET_ADMIN", "NET_RAW"},
+			},
+		},
+	}, left, nil
+}
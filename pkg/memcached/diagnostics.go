@@ -0,0 +1,53 @@
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statsTimeout - limit for connecting to a memcached server and reading its stats
+const statsTimeout = 5 * time.Second
+
+// Stats returns the output of the memcached stats command of the server at host
+func Stats(ctx context.Context, host string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, statsTimeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(MemcachedPort)))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := conn.Write([]byte("stats\r\n")); err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "END" {
+			return out.String(), nil
+		}
+		if strings.HasPrefix(line, "ERROR") || strings.HasPrefix(line, "SERVER_ERROR") {
+			return "", fmt.Errorf("stats failed: %s", line)
+		}
+		out.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("stats output incomplete")
+}
@@ -0,0 +1,76 @@
+package memcached
+
+import (
+	"fmt"
+	"os"
+
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// FailureInjectionEnv - operator env var, if "true" the failureInjection of
+// Memcached CRs is honored
+const FailureInjectionEnv = "MEMCACHED_FAILURE_INJECTION"
+
+// failureInjection returns the failure injection of the CR, or nil if failure
+// injection is not enabled on the operator
+func failureInjection(m *memcachedv1.Memcached) *memcachedv1.FailureInjection {
+	if os.Getenv(FailureInjectionEnv) != "true" {
+		return nil
+	}
+	return m.Spec.FailureInjection
+}
+
+// injectPodFailures changes the memcached container to start slowly and/or to
+// flap its readiness, as requested by the failure injection of the CR
+func injectPodFailures(m *memcachedv1.Memcached, c *corev1.Container) {
+	fi := failureInjection(m)
+	if fi == nil {
+		return
+	}
+
+	if fi.StartDelaySeconds > 0 {
+		c.Command = []string{"/usr/bin/dumb-init", "--", "/bin/sh", "-c",
+			fmt.Sprintf("sleep %d && exec /usr/local/bin/kolla_start", fi.StartDelaySeconds)}
+	}
+	if fi.ReadinessFlapPeriodSeconds > 0 {
+		c.ReadinessProbe.TCPSocket = nil
+		c.ReadinessProbe.Exec = &corev1.ExecAction{
+			Command: []string{"/bin/sh", "-c",
+				fmt.Sprintf("[ $(( $(date +%%s) / %d %% 2 )) -eq 0 ]", fi.ReadinessFlapPeriodSeconds)},
+		}
+	}
+}
+
+// droppedServers returns the ordinals of the replicas the failure injection of
+// the CR drops from the server lists
+func droppedServers(m *memcachedv1.Memcached) map[int32]bool {
+	dropped := map[int32]bool{}
+	if fi := failureInjection(m); fi != nil {
+		for _, o := range fi.DropServers {
+			dropped[o] = true
+		}
+	}
+	return dropped
+}
+
+// InjectServerFailures removes the replicas dropped by the failure injection of
+// the CR from the server lists in the status
+func InjectServerFailures(m *memcachedv1.Memcached) {
+	dropped := droppedServers(m)
+	if len(dropped) == 0 {
+		return
+	}
+
+	s := &m.Status
+	serverList, serverListWithInet, servers := []string{}, []string{}, []memcachedv1.MemcachedServer{}
+	for i := range s.ServerList {
+		if dropped[int32(i)] {
+			continue
+		}
+		serverList = append(serverList, s.ServerList[i])
+		serverListWithInet = append(serverListWithInet, s.ServerListWithInet[i])
+		servers = append(servers, s.Servers[i])
+	}
+	s.ServerList, s.ServerListWithInet, s.Servers = serverList, serverListWithInet, servers
+}
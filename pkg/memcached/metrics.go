@@ -0,0 +1,83 @@
+package memcached
+
+import (
+	"fmt"
+
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// ExporterPort - port the memcached_exporter sidecar serves the metrics on
+	ExporterPort = 9150
+
+	// exporterPortName - name of the metrics port of the pods and the service
+	exporterPortName = "metrics"
+)
+
+// ServiceMonitorGVK - kind of the Prometheus operator ServiceMonitor
+var ServiceMonitorGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "ServiceMonitor",
+}
+
+// exporterContainer returns the memcached_exporter sidecar scraping the memcached
+// of its pod
+func exporterContainer(m *memcachedv1.Memcached) corev1.Container {
+	probe := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: "/metrics",
+				Port: intstr.FromInt(ExporterPort),
+			},
+		},
+		TimeoutSeconds: 5,
+		PeriodSeconds:  10,
+	}
+
+	return corev1.Container{
+		Name:  "exporter",
+		Image: m.Spec.Metrics.ExporterImage,
+		Args: []string{
+			fmt.Sprintf("--memcached.address=localhost:%d", MemcachedPort),
+			fmt.Sprintf("--web.listen-address=:%d", ExporterPort),
+		},
+		Ports: []corev1.ContainerPort{{
+			ContainerPort: ExporterPort,
+			Name:          exporterPortName,
+		}},
+		ReadinessProbe: probe,
+		LivenessProbe:  probe,
+	}
+}
+
+// ServiceMonitor returns the ServiceMonitor scraping the exporters of the CR
+// via its headless service
+func ServiceMonitor(m *memcachedv1.Memcached, serviceLabels map[string]string) *unstructured.Unstructured {
+	sm := &unstructured.Unstructured{}
+	sm.SetGroupVersionKind(ServiceMonitorGVK)
+	sm.SetName(ResourceName(m))
+	sm.SetNamespace(m.Namespace)
+
+	matchLabels := map[string]interface{}{}
+	for k, v := range serviceLabels {
+		matchLabels[k] = v
+	}
+	sm.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": matchLabels,
+		},
+		"endpoints": []interface{}{
+			map[string]interface{}{
+				"port":     exporterPortName,
+				"interval": m.Spec.Metrics.Interval,
+			},
+		},
+	}
+
+	return sm
+}
@@ -0,0 +1,69 @@
+package memcached
+
+import (
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	naming "github.com/openstack-k8s-operators/infra-operator/pkg/naming"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxResourceNameLength - the statefulset controller adds a hash of the pod
+// template to the pod labels, which fits a label value only up to this length
+const maxResourceNameLength = 52
+
+const (
+	configSecretSuffix      = "-memcached-config-data"
+	diagnosticsSecretSuffix = "-diagnostics"
+	connectionSecretSuffix  = "-memcached-connection"
+)
+
+// ResourceName returns the name of the statefulset and service of the CR
+func ResourceName(m *memcachedv1.Memcached) string {
+	return naming.Name(m.Name)
+}
+
+// ConfigSecretName returns the name of the secret holding the rendered config
+func ConfigSecretName(m *memcachedv1.Memcached) string {
+	return naming.Name(m.Name + configSecretSuffix)
+}
+
+// DiagnosticsSecretName returns the name of the secret diagnostics are collected to
+func DiagnosticsSecretName(m *memcachedv1.Memcached) string {
+	return naming.Name(m.Name + diagnosticsSecretSuffix)
+}
+
+// ConnectionSecretName returns the name of the secret clients get the servers
+// and credentials from
+func ConnectionSecretName(m *memcachedv1.Memcached) string {
+	return naming.Name(m.Name + connectionSecretSuffix)
+}
+
+// ValidateNames returns an error if the generated resource names of the CR are invalid
+func ValidateNames(m *memcachedv1.Memcached) error {
+	return naming.Validate(ResourceName(m), maxResourceNameLength)
+}
+
+// NamedObjects returns the objects generated for the CR, named with affixes a
+func NamedObjects(m *memcachedv1.Memcached, a naming.Affixes) []client.Object {
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: a.Name(name), Namespace: m.Namespace}
+	}
+	sm := &unstructured.Unstructured{}
+	sm.SetGroupVersionKind(ServiceMonitorGVK)
+	sm.SetName(a.Name(m.Name))
+	sm.SetNamespace(m.Namespace)
+
+	return []client.Object{
+		&appsv1.StatefulSet{ObjectMeta: meta(m.Name)},
+		&corev1.Service{ObjectMeta: meta(m.Name)},
+		&policyv1.PodDisruptionBudget{ObjectMeta: meta(m.Name)},
+		&corev1.Secret{ObjectMeta: meta(m.Name + configSecretSuffix)},
+		&corev1.Secret{ObjectMeta: meta(m.Name + diagnosticsSecretSuffix)},
+		&corev1.Secret{ObjectMeta: meta(m.Name + connectionSecretSuffix)},
+		sm,
+	}
+}
@@ -0,0 +1,85 @@
+package memcached
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// networkStatusAnnotation - annotation multus sets on pods with the addresses
+// of all attached networks
+const networkStatusAnnotation = "k8s.v1.cni.cncf.io/network-status"
+
+type networkStatus struct {
+	Name string   `json:"name"`
+	IPs  []string `json:"ips"`
+}
+
+// GetNetworkServerLists returns for each network attachment of the CR the
+// published memcached servers on that network, using the first address the
+// pods got on it. Pods without an address on a network yet are left out.
+func GetNetworkServerLists(m *memcachedv1.Memcached, pods []corev1.Pod) (map[string][]string, error) {
+	if len(m.Spec.NetworkAttachments) == 0 {
+		return nil, nil
+	}
+
+	published := map[int]bool{}
+	for _, o := range PublishedOrdinals(m) {
+		published[int(o)] = true
+	}
+
+	sort.Slice(pods, func(i, j int) bool {
+		return podOrdinal(pods[i].Name) < podOrdinal(pods[j].Name)
+	})
+
+	lists := map[string][]string{}
+	for _, nad := range m.Spec.NetworkAttachments {
+		lists[nad] = []string{}
+	}
+	for _, pod := range pods {
+		if !published[podOrdinal(pod.Name)] {
+			continue
+		}
+		data, ok := pod.Annotations[networkStatusAnnotation]
+		if !ok {
+			continue
+		}
+		statuses := []networkStatus{}
+		if err := json.Unmarshal([]byte(data), &statuses); err != nil {
+			return nil, fmt.Errorf("error parsing network status of pod %s: %w", pod.Name, err)
+		}
+		for _, nad := range m.Spec.NetworkAttachments {
+			for _, s := range statuses {
+				if s.Name != nad && s.Name != m.Namespace+"/"+nad {
+					continue
+				}
+				if len(s.IPs) == 0 {
+					break
+				}
+				server := fmt.Sprintf("%s:%d", s.IPs[0], MemcachedPort)
+				if ip := net.ParseIP(s.IPs[0]); ip != nil && ip.To4() == nil {
+					server = fmt.Sprintf("[%s]:%d", s.IPs[0], MemcachedPort)
+				}
+				lists[nad] = append(lists[nad], server)
+				break
+			}
+		}
+	}
+
+	return lists, nil
+}
+
+// podOrdinal returns the ordinal of a statefulset pod from its name
+func podOrdinal(name string) int {
+	i, err := strconv.Atoi(name[strings.LastIndex(name, "-")+1:])
+	if err != nil {
+		return -1
+	}
+	return i
+}
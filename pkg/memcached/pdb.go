@@ -0,0 +1,33 @@
+package memcached
+
+import (
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	inherit "github.com/openstack-k8s-operators/infra-operator/pkg/inherit"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// PodDisruptionBudget returns the PodDisruptionBudget limiting the memcached pods
+// of the CR a node drain may evict at once, selecting the pods by podLabels
+func PodDisruptionBudget(m *memcachedv1.Memcached, podLabels map[string]string) *policyv1.PodDisruptionBudget {
+	maxUnavailable := intstr.FromInt(1)
+	if m.Spec.MaxUnavailable != nil {
+		maxUnavailable = *m.Spec.MaxUnavailable
+	}
+
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ResourceName(m),
+			Namespace:   m.Namespace,
+			Labels:      inherit.Labels(m, m.Spec.InheritMetadataPrefixes),
+			Annotations: inherit.Annotations(m, m.Spec.InheritMetadataPrefixes),
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: podLabels,
+			},
+		},
+	}
+}
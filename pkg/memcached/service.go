@@ -1,21 +1,29 @@
 package memcached
 
 import (
+	"fmt"
+	"time"
+
 	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	inherit "github.com/openstack-k8s-operators/infra-operator/pkg/inherit"
 	labels "github.com/openstack-k8s-operators/lib-common/modules/common/labels"
 	service "github.com/openstack-k8s-operators/lib-common/modules/common/service"
+	util "github.com/openstack-k8s-operators/lib-common/modules/common/util"
 	corev1 "k8s.io/api/core/v1"
 )
 
 // HeadlessService exposes all memcached repliscas for a memcached CR
 func HeadlessService(m *memcachedv1.Memcached) *corev1.Service {
-	labels := labels.GetLabels(m, "memcached", map[string]string{
-		"owner": "infra-operator",
-		"cr":    m.GetName(),
-		"app":   "memcached",
-	})
+	labels := labels.GetLabels(m, "memcached", util.MergeStringMaps(
+		inherit.Labels(m, m.Spec.InheritMetadataPrefixes),
+		map[string]string{
+			"owner": "infra-operator",
+			"cr":    m.GetName(),
+			"app":   "memcached",
+		},
+	))
 	details := &service.GenericServiceDetails{
-		Name:      m.GetName(),
+		Name:      ResourceName(m),
 		Namespace: m.GetNamespace(),
 		Labels:    labels,
 		Selector: map[string]string{
@@ -23,12 +31,118 @@ func HeadlessService(m *memcachedv1.Memcached) *corev1.Service {
 		},
 		Port: service.GenericServicePort{
 			Name:     "memcached",
-			Port:     11211,
+			Port:     MemcachedPort,
 			Protocol: "TCP",
 		},
 		ClusterIP: "None",
 	}
 
 	svc := service.GenericService(details)
+	if m.Spec.Metrics.Enabled {
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name:     exporterPortName,
+			Port:     ExporterPort,
+			Protocol: corev1.ProtocolTCP,
+		})
+	}
+	svc.Annotations = inherit.Annotations(m, m.Spec.InheritMetadataPrefixes)
+	if m.Spec.IPFamily != "" {
+		ipFamilyPolicy := corev1.IPFamilyPolicySingleStack
+		svc.Spec.IPFamilyPolicy = &ipFamilyPolicy
+		svc.Spec.IPFamilies = []corev1.IPFamily{m.Spec.IPFamily}
+	}
+
 	return svc
 }
+
+// ManagesService returns true if the operator manages the service of the CR
+func ManagesService(m *memcachedv1.Memcached) bool {
+	return m.Spec.Manage.Service == nil || *m.Spec.Manage.Service
+}
+
+// GetServerLists returns the first replicas memcached servers of the CR, without and
+// with the inet(6) prefix python-memcached needs to select the address family
+func GetServerLists(m *memcachedv1.Memcached, replicas int32, ipFamily corev1.IPFamily) ([]string, []string) {
+	suffix := fmt.Sprintf("%s.svc", m.Namespace)
+	if m.Spec.ServerHostnameSuffix != "" {
+		suffix = m.Spec.ServerHostnameSuffix
+	}
+
+	serverList := []string{}
+	serverListWithInet := []string{}
+	for i := int32(0); i < replicas; i++ {
+		server := fmt.Sprintf("%s-%d.%s.%s", ResourceName(m), i, ResourceName(m), suffix)
+		serverList = append(serverList, fmt.Sprintf("%s:%d", server, MemcachedPort))
+		if ipFamily == corev1.IPv6Protocol {
+			serverListWithInet = append(serverListWithInet, fmt.Sprintf("inet6:[%s]:%d", server, MemcachedPort))
+		} else {
+			serverListWithInet = append(serverListWithInet, fmt.Sprintf("inet:%s:%d", server, MemcachedPort))
+		}
+	}
+
+	return serverList, serverListWithInet
+}
+
+// GetServers returns the memcached servers of the CR with their weight
+func GetServers(m *memcachedv1.Memcached, serverList []string) []memcachedv1.MemcachedServer {
+	servers := []memcachedv1.MemcachedServer{}
+	for i, server := range serverList {
+		weight := int32(1)
+		if i < len(m.Spec.ServerWeights) && m.Spec.ServerWeights[i] > 1 {
+			weight = m.Spec.ServerWeights[i]
+		}
+		servers = append(servers, memcachedv1.MemcachedServer{
+			Address: server,
+			Weight:  weight,
+		})
+	}
+
+	return servers
+}
+
+// PublishedReplicas returns the number of replicas to publish in the server lists
+// and the time until the next one is due, adding at most one replica per
+// ScaleOutInterval of the CR on scale-out
+func PublishedReplicas(m *memcachedv1.Memcached, now time.Time) (int32, time.Duration) {
+	published := m.Status.PublishedReplicas
+	interval := m.Spec.ScaleOutInterval
+	if interval == nil || interval.Duration == 0 || published == 0 || m.Spec.Replicas <= published {
+		return m.Spec.Replicas, 0
+	}
+
+	if m.Status.LastPublishTime != nil {
+		if wait := m.Status.LastPublishTime.Add(interval.Duration).Sub(now); wait > 0 {
+			return published, wait
+		}
+	}
+	published++
+	if published < m.Spec.Replicas {
+		return published, interval.Duration
+	}
+
+	return published, 0
+}
+
+// PublishedOrdinals returns the ordinals of the replicas in the server lists of
+// the status, in their order
+func PublishedOrdinals(m *memcachedv1.Memcached) []int32 {
+	dropped := droppedServers(m)
+	ordinals := []int32{}
+	for i := int32(0); i < m.Status.PublishedReplicas; i++ {
+		if !dropped[i] {
+			ordinals = append(ordinals, i)
+		}
+	}
+	return ordinals
+}
+
+// SetServerZones sets the zone of the published servers from the zones of
+// their pods, by pod name
+func SetServerZones(m *memcachedv1.Memcached, zones map[string]string) {
+	for i, ordinal := range PublishedOrdinals(m) {
+		if i >= len(m.Status.Servers) {
+			break
+		}
+		m.Status.Servers[i].Zone = zones[fmt.Sprintf("%s-%d", ResourceName(m), ordinal)]
+	}
+}
@@ -0,0 +1,259 @@
+package memcached
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	naming "github.com/openstack-k8s-operators/infra-operator/pkg/naming"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPublishedReplicas(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	minute := &metav1.Duration{Duration: time.Minute}
+	at := func(ago time.Duration) *metav1.Time {
+		return &metav1.Time{Time: now.Add(-ago)}
+	}
+
+	tests := []struct {
+		name          string
+		replicas      int32
+		interval      *metav1.Duration
+		published     int32
+		lastPublish   *metav1.Time
+		wantPublished int32
+		wantNext      time.Duration
+	}{
+		{
+			name:          "no interval publishes all replicas",
+			replicas:      3,
+			published:     1,
+			wantPublished: 3,
+		},
+		{
+			name:          "zero interval publishes all replicas",
+			replicas:      3,
+			interval:      &metav1.Duration{},
+			published:     1,
+			wantPublished: 3,
+		},
+		{
+			name:          "initial deployment publishes all replicas",
+			replicas:      3,
+			interval:      minute,
+			wantPublished: 3,
+		},
+		{
+			name:          "scale-in publishes the new count immediately",
+			replicas:      2,
+			interval:      minute,
+			published:     4,
+			lastPublish:   at(time.Second),
+			wantPublished: 2,
+		},
+		{
+			name:          "scale-out waits for the interval",
+			replicas:      4,
+			interval:      minute,
+			published:     2,
+			lastPublish:   at(20 * time.Second),
+			wantPublished: 2,
+			wantNext:      40 * time.Second,
+		},
+		{
+			name:          "scale-out adds one replica per interval",
+			replicas:      4,
+			interval:      minute,
+			published:     2,
+			lastPublish:   at(time.Minute),
+			wantPublished: 3,
+			wantNext:      time.Minute,
+		},
+		{
+			name:          "scale-out without a publish time adds one replica",
+			replicas:      4,
+			interval:      minute,
+			published:     2,
+			wantPublished: 3,
+			wantNext:      time.Minute,
+		},
+		{
+			name:          "last replica needs no further requeue",
+			replicas:      3,
+			interval:      minute,
+			published:     2,
+			lastPublish:   at(2 * time.Minute),
+			wantPublished: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			m := &memcachedv1.Memcached{}
+			m.Spec.Replicas = tt.replicas
+			m.Spec.ScaleOutInterval = tt.interval
+			m.Status.PublishedReplicas = tt.published
+			m.Status.LastPublishTime = tt.lastPublish
+
+			published, next := PublishedReplicas(m, now)
+			g.Expect(published).To(Equal(tt.wantPublished))
+			g.Expect(next).To(Equal(tt.wantNext))
+		})
+	}
+}
+
+func TestGetServerLists(t *testing.T) {
+	tests := []struct {
+		name         string
+		prefix       string
+		hostSuffix   string
+		replicas     int32
+		ipFamily     corev1.IPFamily
+		wantList     []string
+		wantWithInet []string
+	}{
+		{
+			name:         "no replicas",
+			ipFamily:     corev1.IPv4Protocol,
+			wantList:     []string{},
+			wantWithInet: []string{},
+		},
+		{
+			name:     "IPv4",
+			replicas: 2,
+			ipFamily: corev1.IPv4Protocol,
+			wantList: []string{
+				"memcached-0.memcached.openstack.svc:11211",
+				"memcached-1.memcached.openstack.svc:11211",
+			},
+			wantWithInet: []string{
+				"inet:memcached-0.memcached.openstack.svc:11211",
+				"inet:memcached-1.memcached.openstack.svc:11211",
+			},
+		},
+		{
+			name:     "unknown family defaults to inet",
+			replicas: 1,
+			wantList: []string{
+				"memcached-0.memcached.openstack.svc:11211",
+			},
+			wantWithInet: []string{
+				"inet:memcached-0.memcached.openstack.svc:11211",
+			},
+		},
+		{
+			name:     "IPv6 brackets the host",
+			replicas: 2,
+			ipFamily: corev1.IPv6Protocol,
+			wantList: []string{
+				"memcached-0.memcached.openstack.svc:11211",
+				"memcached-1.memcached.openstack.svc:11211",
+			},
+			wantWithInet: []string{
+				"inet6:[memcached-0.memcached.openstack.svc]:11211",
+				"inet6:[memcached-1.memcached.openstack.svc]:11211",
+			},
+		},
+		{
+			name:       "hostname suffix",
+			replicas:   1,
+			hostSuffix: "openstack.svc.cluster.local",
+			ipFamily:   corev1.IPv6Protocol,
+			wantList: []string{
+				"memcached-0.memcached.openstack.svc.cluster.local:11211",
+			},
+			wantWithInet: []string{
+				"inet6:[memcached-0.memcached.openstack.svc.cluster.local]:11211",
+			},
+		},
+		{
+			name:     "name prefix",
+			prefix:   "infra-",
+			replicas: 1,
+			ipFamily: corev1.IPv4Protocol,
+			wantList: []string{
+				"infra-memcached-0.infra-memcached.openstack.svc:11211",
+			},
+			wantWithInet: []string{
+				"inet:infra-memcached-0.infra-memcached.openstack.svc:11211",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Setenv(naming.PrefixEnv, tt.prefix)
+			m := &memcachedv1.Memcached{
+				ObjectMeta: metav1.ObjectMeta{Name: "memcached", Namespace: "openstack"},
+			}
+			m.Spec.ServerHostnameSuffix = tt.hostSuffix
+
+			serverList, serverListWithInet := GetServerLists(m, tt.replicas, tt.ipFamily)
+			g.Expect(serverList).To(Equal(tt.wantList))
+			g.Expect(serverListWithInet).To(Equal(tt.wantWithInet))
+		})
+	}
+}
+
+func TestSetServerZones(t *testing.T) {
+	zones := map[string]string{
+		"memcached-0": "zone-a",
+		"memcached-1": "zone-b",
+		"memcached-2": "zone-c",
+	}
+
+	tests := []struct {
+		name      string
+		published int32
+		dropped   []int32
+		want      []string
+	}{
+		{
+			name:      "all servers",
+			published: 3,
+			want:      []string{"zone-a", "zone-b", "zone-c"},
+		},
+		{
+			name:      "unpublished replicas are left out",
+			published: 2,
+			want:      []string{"zone-a", "zone-b"},
+		},
+		{
+			name:      "dropped servers keep the zones of the others by ordinal",
+			published: 3,
+			dropped:   []int32{1},
+			want:      []string{"zone-a", "zone-c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			if len(tt.dropped) > 0 {
+				t.Setenv(FailureInjectionEnv, "true")
+			}
+			m := &memcachedv1.Memcached{
+				ObjectMeta: metav1.ObjectMeta{Name: "memcached", Namespace: "openstack"},
+			}
+			m.Spec.Replicas = 3
+			m.Spec.FailureInjection = &memcachedv1.FailureInjection{DropServers: tt.dropped}
+			m.Status.PublishedReplicas = tt.published
+			m.Status.ServerList, m.Status.ServerListWithInet = GetServerLists(m, tt.published, corev1.IPv4Protocol)
+			m.Status.Servers = GetServers(m, m.Status.ServerList)
+			InjectServerFailures(m)
+
+			SetServerZones(m, zones)
+			got := []string{}
+			for _, s := range m.Status.Servers {
+				got = append(got, s.Zone)
+			}
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
@@ -1,24 +1,39 @@
 package memcached
 
 import (
+	"fmt"
+	"reflect"
+
 	memcachedv1 "github.com/openstack-k8s-operators/infra-operator/apis/memcached/v1beta1"
+	inherit "github.com/openstack-k8s-operators/infra-operator/pkg/inherit"
+	inputhash "github.com/openstack-k8s-operators/infra-operator/pkg/inputhash"
 	labels "github.com/openstack-k8s-operators/lib-common/modules/common/labels"
+	util "github.com/openstack-k8s-operators/lib-common/modules/common/util"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-// StatefulSet returns a Stateful resource for the Memcached CR
-func StatefulSet(m *memcachedv1.Memcached) *appsv1.StatefulSet {
+// StatefulSet returns a Stateful resource for the Memcached CR, with the pods
+// attached to the networks of nadAnnotations
+func StatefulSet(m *memcachedv1.Memcached, configHash string, nadAnnotations map[string]string) *appsv1.StatefulSet {
 	matchls := map[string]string{
 		"app":   "memcached",
 		"cr":    "memcached-" + m.Name,
 		"owner": "infra-operator",
 	}
-	ls := labels.GetLabels(m, "memcached", matchls)
+	ls := labels.GetLabels(m, "memcached", util.MergeStringMaps(
+		inherit.Labels(m, m.Spec.InheritMetadataPrefixes), matchls))
+	annotations := inherit.Annotations(m, m.Spec.InheritMetadataPrefixes)
 	replicas := m.Spec.Replicas
 	runAsUser := int64(0)
+	securityContext := &corev1.SecurityContext{
+		RunAsUser: &runAsUser,
+	}
+	if m.Spec.SecurityContext != nil {
+		securityContext = m.Spec.SecurityContext
+	}
 
 	livenessProbe := &corev1.Probe{
 		// TODO might need tuning
@@ -35,40 +50,45 @@ func StatefulSet(m *memcachedv1.Memcached) *appsv1.StatefulSet {
 
 	// TODO might want to disable probes in 'Debug' mode
 	livenessProbe.TCPSocket = &corev1.TCPSocketAction{
-		Port: intstr.IntOrString{Type: intstr.Int, IntVal: int32(11211)},
+		Port: intstr.IntOrString{Type: intstr.Int, IntVal: MemcachedPort},
 	}
 	readinessProbe.TCPSocket = &corev1.TCPSocketAction{
-		Port: intstr.IntOrString{Type: intstr.Int, IntVal: int32(11211)},
+		Port: intstr.IntOrString{Type: intstr.Int, IntVal: MemcachedPort},
 	}
 
 	sfs := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      m.Name,
-			Namespace: m.Namespace,
+			Name:        ResourceName(m),
+			Namespace:   m.Namespace,
+			Labels:      ls,
+			Annotations: annotations,
 		},
 		Spec: appsv1.StatefulSetSpec{
-			ServiceName: m.Name,
+			ServiceName: ResourceName(m),
 			Replicas:    &replicas,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: matchls,
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: ls,
+					Labels:      ls,
+					Annotations: util.MergeStringMaps(annotations, nadAnnotations),
 				},
 				Spec: corev1.PodSpec{
-					ServiceAccountName: "mariadb-operator-mariadb",
+					ServiceAccountName:        "mariadb-operator-mariadb",
+					PriorityClassName:         m.Spec.PriorityClassName,
+					Affinity:                  podAffinity(m, matchls),
+					TopologySpreadConstraints: topologySpreadConstraints(matchls),
+					SecurityContext:           m.Spec.PodSecurityContext,
 					Containers: []corev1.Container{{
-						Image:   m.Spec.ContainerImage,
-						Name:    "memcached",
-						Command: []string{"/usr/bin/dumb-init", "--", "/usr/local/bin/kolla_start"},
-						SecurityContext: &corev1.SecurityContext{
-							RunAsUser: &runAsUser,
-						},
+						Image:           Image(m),
+						Name:            "memcached",
+						Command:         []string{"/usr/bin/dumb-init", "--", "/usr/local/bin/kolla_start"},
+						SecurityContext: securityContext,
 						Env: []corev1.EnvVar{{
 							Name:  "KOLLA_CONFIG_STRATEGY",
 							Value: "COPY_ALWAYS",
-						}},
+						}, inputhash.EnvVar(configHash)},
 						VolumeMounts: []corev1.VolumeMount{{
 							MountPath: "/var/lib/kolla/config_files/src",
 							ReadOnly:  true,
@@ -79,9 +99,10 @@ func StatefulSet(m *memcachedv1.Memcached) *appsv1.StatefulSet {
 							Name:      "kolla-config",
 						}},
 						Ports: []corev1.ContainerPort{{
-							ContainerPort: 11211,
+							ContainerPort: MemcachedPort,
 							Name:          "memcached",
 						}},
+						Resources:      m.Spec.Resources,
 						ReadinessProbe: readinessProbe,
 						LivenessProbe:  livenessProbe,
 					}},
@@ -89,10 +110,8 @@ func StatefulSet(m *memcachedv1.Memcached) *appsv1.StatefulSet {
 						{
 							Name: "kolla-config",
 							VolumeSource: corev1.VolumeSource{
-								ConfigMap: &corev1.ConfigMapVolumeSource{
-									LocalObjectReference: corev1.LocalObjectReference{
-										Name: m.Name + "-memcached-config-data",
-									},
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: ConfigSecretName(m),
 									Items: []corev1.KeyToPath{
 										{
 											Key:  "config.json",
@@ -105,10 +124,8 @@ func StatefulSet(m *memcachedv1.Memcached) *appsv1.StatefulSet {
 						{
 							Name: "config-data",
 							VolumeSource: corev1.VolumeSource{
-								ConfigMap: &corev1.ConfigMapVolumeSource{
-									LocalObjectReference: corev1.LocalObjectReference{
-										Name: m.Name + "-memcached-config-data",
-									},
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: ConfigSecretName(m),
 									Items: []corev1.KeyToPath{
 										{
 											Key:  "memcached",
@@ -124,5 +141,125 @@ func StatefulSet(m *memcachedv1.Memcached) *appsv1.StatefulSet {
 		},
 	}
 
+	if m.Spec.Extstore != nil {
+		addExtstore(m, sfs)
+	}
+	if m.Spec.AuthEnabled {
+		addAuth(m, sfs)
+	}
+	injectPodFailures(m, &sfs.Spec.Template.Spec.Containers[0])
+	if m.Spec.Metrics.Enabled {
+		sfs.Spec.Template.Spec.Containers = append(sfs.Spec.Template.Spec.Containers, exporterContainer(m))
+	}
+	sfs.Spec.Template.Spec.Containers = append(sfs.Spec.Template.Spec.Containers, m.Spec.ExtraContainers...)
+	sfs.Spec.Template.Spec.Volumes = append(sfs.Spec.Template.Spec.Volumes, m.Spec.ExtraVolumes...)
+
 	return sfs
 }
+
+// ValidateExtras returns an error if the extra containers or volumes of the
+// Memcached CR clash with the containers and volumes managed by the operator,
+// or with each other. Container names are unique across init containers and
+// containers.
+func ValidateExtras(m *memcachedv1.Memcached) error {
+	managed := m.DeepCopy()
+	managed.Spec.ExtraContainers = nil
+	managed.Spec.ExtraVolumes = nil
+	podSpec := StatefulSet(managed, "", nil).Spec.Template.Spec
+
+	containers := map[string]bool{}
+	for _, c := range append(podSpec.InitContainers, podSpec.Containers...) {
+		containers[c.Name] = true
+	}
+	for _, extra := range m.Spec.ExtraContainers {
+		if containers[extra.Name] {
+			return fmt.Errorf("extra container %s clashes with another container", extra.Name)
+		}
+		containers[extra.Name] = true
+	}
+
+	volumes := map[string]bool{}
+	for _, v := range podSpec.Volumes {
+		volumes[v.Name] = true
+	}
+	for _, extra := range m.Spec.ExtraVolumes {
+		if volumes[extra.Name] {
+			return fmt.Errorf("extra volume %s clashes with another volume", extra.Name)
+		}
+		volumes[extra.Name] = true
+	}
+
+	return nil
+}
+
+// addExtstore mounts the extstore volume of each replica, from a per replica
+// persistent volume claim or a per pod sub directory of a host path
+func addExtstore(m *memcachedv1.Memcached, sfs *appsv1.StatefulSet) {
+	container := &sfs.Spec.Template.Spec.Containers[0]
+	mount := corev1.VolumeMount{
+		MountPath: ExtstorePath,
+		Name:      "extstore",
+	}
+
+	if m.Spec.Extstore.HostPath != "" {
+		hostPathType := corev1.HostPathDirectoryOrCreate
+		sfs.Spec.Template.Spec.Volumes = append(sfs.Spec.Template.Spec.Volumes, corev1.Volume{
+			Name: "extstore",
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: m.Spec.Extstore.HostPath,
+					Type: &hostPathType,
+				},
+			},
+		})
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name: "POD_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			},
+		})
+		mount.SubPathExpr = "$(POD_NAME)"
+	} else {
+		sfs.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "extstore",
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				StorageClassName: m.Spec.Extstore.StorageClass,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: m.Spec.Extstore.Size,
+					},
+				},
+			},
+		}}
+	}
+
+	container.VolumeMounts = append(container.VolumeMounts, mount)
+}
+
+// VolumeClaimTemplatesChanged compares the volume claim templates of the
+// existing and the desired StatefulSet, which can't be patched. Returns true if
+// claims got added or removed, which needs the StatefulSet to be recreated, or
+// an error if the size or storage class of existing claims changed.
+func VolumeClaimTemplatesChanged(current *appsv1.StatefulSet, desired *appsv1.StatefulSet) (bool, error) {
+	claims := map[string]corev1.PersistentVolumeClaim{}
+	for _, c := range current.Spec.VolumeClaimTemplates {
+		claims[c.Name] = c
+	}
+	if len(claims) != len(desired.Spec.VolumeClaimTemplates) {
+		return true, nil
+	}
+	for _, d := range desired.Spec.VolumeClaimTemplates {
+		c, ok := claims[d.Name]
+		if !ok {
+			return true, nil
+		}
+		if !c.Spec.Resources.Requests.Storage().Equal(*d.Spec.Resources.Requests.Storage()) ||
+			!reflect.DeepEqual(c.Spec.StorageClassName, d.Spec.StorageClassName) {
+			return false, fmt.Errorf("the size and storage class of the %s volume claims can't be changed", d.Name)
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,103 @@
+package memcached
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVolumeClaimTemplatesChanged(t *testing.T) {
+	fast, slow := "fast", "slow"
+	claim := func(name string, size string, class *string) corev1.PersistentVolumeClaim {
+		return corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: class,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+				},
+			},
+		}
+	}
+	sfs := func(claims ...corev1.PersistentVolumeClaim) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{VolumeClaimTemplates: claims}}
+	}
+
+	tests := []struct {
+		name        string
+		current     *appsv1.StatefulSet
+		desired     *appsv1.StatefulSet
+		wantChanged bool
+		wantErr     bool
+	}{
+		{
+			name:    "no claims",
+			current: sfs(),
+			desired: sfs(),
+		},
+		{
+			name:    "same claims",
+			current: sfs(claim("extstore", "1Gi", &fast)),
+			desired: sfs(claim("extstore", "1Gi", &fast)),
+		},
+		{
+			name:    "equal sizes in other units",
+			current: sfs(claim("extstore", "1024Mi", nil)),
+			desired: sfs(claim("extstore", "1Gi", nil)),
+		},
+		{
+			name:        "claim added",
+			current:     sfs(),
+			desired:     sfs(claim("extstore", "1Gi", nil)),
+			wantChanged: true,
+		},
+		{
+			name:        "claim removed",
+			current:     sfs(claim("extstore", "1Gi", nil)),
+			desired:     sfs(),
+			wantChanged: true,
+		},
+		{
+			name:        "claim renamed",
+			current:     sfs(claim("extstore", "1Gi", nil)),
+			desired:     sfs(claim("data", "1Gi", nil)),
+			wantChanged: true,
+		},
+		{
+			name:    "size changed",
+			current: sfs(claim("extstore", "1Gi", nil)),
+			desired: sfs(claim("extstore", "2Gi", nil)),
+			wantErr: true,
+		},
+		{
+			name:    "storage class changed",
+			current: sfs(claim("extstore", "1Gi", &fast)),
+			desired: sfs(claim("extstore", "1Gi", &slow)),
+			wantErr: true,
+		},
+		{
+			name:    "storage class set",
+			current: sfs(claim("extstore", "1Gi", nil)),
+			desired: sfs(claim("extstore", "1Gi", &fast)),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			changed, err := VolumeClaimTemplatesChanged(tt.current, tt.desired)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(changed).To(Equal(tt.wantChanged))
+		})
+	}
+}
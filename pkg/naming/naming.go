@@ -0,0 +1,91 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package naming
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// PrefixEnv - operator env var, prefix added to the names of all generated resources
+	PrefixEnv = "RESOURCE_NAME_PREFIX"
+
+	// SuffixEnv - operator env var, suffix added to the names of all generated resources
+	SuffixEnv = "RESOURCE_NAME_SUFFIX"
+)
+
+// Affixes - prefix and suffix applied to the names of generated resources
+type Affixes struct {
+	Prefix string
+	Suffix string
+}
+
+// Current returns the affixes the operator is configured with
+func Current() Affixes {
+	return Affixes{Prefix: os.Getenv(PrefixEnv), Suffix: os.Getenv(SuffixEnv)}
+}
+
+// Name returns base with the affixes applied
+func (a Affixes) Name(base string) string {
+	return a.Prefix + base + a.Suffix
+}
+
+// Name returns the name of a generated resource with the operator wide
+// prefix and suffix applied to base. Controllers record the affixes they
+// named resources with, to clean up the old resources when they change.
+func Name(base string) string {
+	return Current().Name(base)
+}
+
+// Validate returns an error if name is not a DNS label of at most maxLen
+// characters, e.g. because the prefix or suffix made it too long
+func Validate(name string, maxLen int) error {
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return fmt.Errorf("invalid resource name %s: %s", name, strings.Join(errs, ", "))
+	}
+	if len(name) > maxLen {
+		return fmt.Errorf("resource name %s is longer than %d characters", name, maxLen)
+	}
+	return nil
+}
+
+// VerifyOwner returns an error if an object with the name and namespace of obj
+// exists which is not controlled by owner, e.g. because the generated names of
+// two CRs collide
+func VerifyOwner(ctx context.Context, c client.Reader, obj client.Object, owner metav1.Object) error {
+	key := types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}
+	err := c.Get(ctx, key, obj)
+	if k8s_errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if ref := metav1.GetControllerOf(obj); ref == nil || ref.UID != owner.GetUID() {
+		return fmt.Errorf("%T %s already exists and is not owned by %s", obj, obj.GetName(), owner.GetName())
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package naming
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestName(t *testing.T) {
+	g := NewWithT(t)
+	t.Setenv(PrefixEnv, "infra-")
+	t.Setenv(SuffixEnv, "-a")
+
+	g.Expect(Name("memcached")).To(Equal("infra-memcached-a"))
+	g.Expect(Affixes{}.Name("memcached")).To(Equal("memcached"))
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		maxLen   int
+		wantErr  bool
+	}{
+		{
+			name:     "valid",
+			resource: "memcached",
+			maxLen:   52,
+		},
+		{
+			name:     "exactly the maximum length",
+			resource: strings.Repeat("a", 52),
+			maxLen:   52,
+		},
+		{
+			name:     "too long",
+			resource: strings.Repeat("a", 53),
+			maxLen:   52,
+			wantErr:  true,
+		},
+		{
+			name:     "upper case",
+			resource: "Memcached",
+			maxLen:   52,
+			wantErr:  true,
+		},
+		{
+			name:     "dots are not allowed in a label",
+			resource: "infra.memcached",
+			maxLen:   52,
+			wantErr:  true,
+		},
+		{
+			name:     "leading dash from an empty base",
+			resource: "-memcached",
+			maxLen:   52,
+			wantErr:  true,
+		},
+		{
+			name:     "empty",
+			resource: "",
+			maxLen:   52,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := Validate(tt.resource, tt.maxLen)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
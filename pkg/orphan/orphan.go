@@ -0,0 +1,165 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orphan
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+var orphaned = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "infra_operator_orphaned_objects",
+		Help: "Objects labeled by the operator whose owning CR does not exist, by kind",
+	},
+	[]string{"kind"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(orphaned)
+}
+
+// Rule - describes one kind of object created by a controller and how to
+// find the CR owning it
+type Rule struct {
+	// Kind - name of the kind used in metrics and logs
+	Kind string
+	// List - returns an empty list of the kind of object to audit
+	List func() client.ObjectList
+	// NameLabel - label holding the name of the owning CR
+	NameLabel string
+	// Owner - returns an empty object of the owning CR kind
+	Owner func() client.Object
+}
+
+// Auditor periodically looks for objects carrying the labels of a Rule which
+// are not controlled by a CR. It adopts objects whose CR exists and reports
+// those whose CR is gone via Events and the infra_operator_orphaned_objects
+// metric.
+type Auditor struct {
+	Client   client.Client
+	Reader   client.Reader
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	Log      logr.Logger
+	Interval time.Duration
+	Rules    []Rule
+}
+
+// NeedLeaderElection - only the leader adopts objects
+func (a *Auditor) NeedLeaderElection() bool {
+	return true
+}
+
+// Start runs the audit at startup and then every Interval until ctx is done
+func (a *Auditor) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, a.audit, a.Interval)
+	return nil
+}
+
+func (a *Auditor) audit(ctx context.Context) {
+	for _, rule := range a.Rules {
+		count, err := a.auditRule(ctx, rule)
+		if err != nil {
+			a.Log.Error(err, "orphan audit failed", "kind", rule.Kind)
+			continue
+		}
+		orphaned.WithLabelValues(rule.Kind).Set(float64(count))
+	}
+}
+
+// auditRule returns the number of objects of the rule without an owning CR
+func (a *Auditor) auditRule(ctx context.Context, rule Rule) (int, error) {
+	list := rule.List()
+	if err := a.Reader.List(ctx, list, client.HasLabels{rule.NameLabel}); err != nil {
+		return 0, err
+	}
+	items, err := listItems(list)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, obj := range items {
+		if metav1.GetControllerOf(obj) != nil || obj.GetDeletionTimestamp() != nil {
+			continue
+		}
+
+		owner := rule.Owner()
+		key := types.NamespacedName{Name: obj.GetLabels()[rule.NameLabel], Namespace: obj.GetNamespace()}
+		err := a.Reader.Get(ctx, key, owner)
+		if k8s_errors.IsNotFound(err) || (err == nil && owner.GetDeletionTimestamp() != nil) {
+			count++
+			a.Log.Info("orphaned object", "kind", rule.Kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+			a.Recorder.Eventf(obj, corev1.EventTypeWarning, "Orphaned",
+				"%s %s is not owned by any CR, %s was not found", rule.Kind, obj.GetName(), key.Name)
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if err := a.adopt(ctx, owner, obj); err != nil {
+			return 0, fmt.Errorf("error adopting %s %s/%s: %w", rule.Kind, obj.GetNamespace(), obj.GetName(), err)
+		}
+		a.Log.Info("adopted object", "kind", rule.Kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+		a.Recorder.Eventf(owner, corev1.EventTypeNormal, "Adopted", "Adopted %s %s", rule.Kind, obj.GetName())
+	}
+
+	return count, nil
+}
+
+func (a *Auditor) adopt(ctx context.Context, owner client.Object, obj client.Object) error {
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	if err := controllerutil.SetControllerReference(owner, obj, a.Scheme); err != nil {
+		return err
+	}
+	return a.Client.Patch(ctx, obj, patch)
+}
+
+func listItems(list client.ObjectList) ([]client.Object, error) {
+	objs, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]client.Object, 0, len(objs))
+	for _, o := range objs {
+		obj, ok := o.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("%T is not a client object", o)
+		}
+		items = append(items, obj)
+	}
+	return items, nil
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package topology
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+// PodZones returns the zone of the node of each scheduled pod, by pod name.
+// Pods not scheduled yet or on nodes without a zone label are left out.
+func PodZones(ctx context.Context, c client.Reader, pods []corev1.Pod) (map[string]string, error) {
+	zones := map[string]string{}
+	nodeZones := map[string]string{}
+	for _, pod := range pods {
+		nodeName := pod.Spec.NodeName
+		if nodeName == "" {
+			continue
+		}
+		zone, ok := nodeZones[nodeName]
+		if !ok {
+			node := &corev1.Node{}
+			err := c.Get(ctx, types.NamespacedName{Name: nodeName}, node)
+			if err != nil && !k8s_errors.IsNotFound(err) {
+				return nil, err
+			}
+			zone = node.Labels[corev1.LabelTopologyZone]
+			nodeZones[nodeName] = zone
+		}
+		if zone != "" {
+			zones[pod.Name] = zone
+		}
+	}
+
+	return zones, nil
+}